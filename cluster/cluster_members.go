@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared"
+
 	"github.com/canonical/microcluster/v3/internal/db/update"
 	"github.com/canonical/microcluster/v3/internal/extensions"
 	"github.com/canonical/microcluster/v3/rest/types"
@@ -38,15 +41,24 @@ const Pending Role = "PENDING"
 
 // CoreClusterMember represents the global database entry for a dqlite cluster member.
 type CoreClusterMember struct {
-	ID             int
-	Name           string `db:"primary=yes"`
-	Address        string
-	Certificate    string
-	SchemaInternal uint64
-	SchemaExternal uint64
-	APIExtensions  extensions.Extensions
-	Heartbeat      time.Time
-	Role           Role
+	ID                int
+	Name              string `db:"primary=yes"`
+	Address           string
+	Certificate       string
+	SchemaInternal    uint64
+	SchemaExternal    uint64
+	APIExtensions     extensions.Extensions
+	Heartbeat         time.Time
+	Role              Role
+	FailureDomain     string
+	Labels            types.Labels
+	HeartbeatFailures int
+	HeartbeatStatus   types.MemberStatus
+	HeartbeatPayload  types.HeartbeatPayload
+	HeartbeatLatency  time.Duration
+	HeartbeatError    string
+	UpgradeStatus     types.MemberStatus
+	Version           string
 }
 
 // CoreClusterMemberFilter is used for filtering queries using generated methods.
@@ -68,6 +80,11 @@ func (c CoreClusterMember) ToAPI() (*types.ClusterMember, error) {
 		return nil, fmt.Errorf("Failed to parse certificate of database cluster member with address %q: %w", c.Address, err)
 	}
 
+	heartbeatStatus := c.HeartbeatStatus
+	if heartbeatStatus == "" {
+		heartbeatStatus = types.MemberOnline
+	}
+
 	return &types.ClusterMember{
 		ClusterMemberLocal: types.ClusterMemberLocal{
 			Name:        c.Name,
@@ -80,6 +97,15 @@ func (c CoreClusterMember) ToAPI() (*types.ClusterMember, error) {
 		LastHeartbeat:         c.Heartbeat,
 		Status:                types.MemberUnreachable,
 		Extensions:            c.APIExtensions,
+		FailureDomain:         c.FailureDomain,
+		Labels:                c.Labels,
+		HeartbeatStatus:       heartbeatStatus,
+		HeartbeatFailures:     c.HeartbeatFailures,
+		HeartbeatPayload:      c.HeartbeatPayload,
+		HeartbeatLatency:      c.HeartbeatLatency,
+		HeartbeatError:        c.HeartbeatError,
+		UpgradeStatus:         c.UpgradeStatus,
+		Version:               c.Version,
 	}, nil
 }
 
@@ -92,21 +118,24 @@ func GetUpgradingClusterMembers(ctx context.Context, tx *sql.Tx, schemaInternal
 		return nil, nil, err
 	}
 
-	// Check for the `api_extensions` column, which may not exist if we haven't actually run the update yet.
+	// Check for columns which may not exist if we haven't actually run the corresponding update yet.
 	stmt := fmt.Sprintf(`
-SELECT count(name)
+SELECT name
 FROM pragma_table_info('%s')
-WHERE name IN ('api_extensions');
+WHERE name IN ('api_extensions', 'failure_domain', 'labels', 'heartbeat_failures', 'heartbeat_status', 'heartbeat_payload', 'heartbeat_latency', 'heartbeat_error', 'upgrade_status', 'version');
 `, tableName)
 
-	var count int
-	err = tx.QueryRow(stmt).Scan(&count)
+	existingColumns, err := query.SelectStrings(ctx, tx, stmt)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	hasColumn := func(name string) bool {
+		return shared.ValueInSlice(name, existingColumns)
+	}
+
 	// Fetch all cluster members with a smaller schema version than we expect.
-	stmt = `SELECT id, name, address, certificate, schema_internal, schema_external, %s, heartbeat, role
+	stmt = `SELECT id, name, address, certificate, schema_internal, schema_external, %s, heartbeat, role, %s, %s, %s, %s, %s, %s, %s, %s, %s
   FROM %s
   ORDER BY name
 	`
@@ -114,11 +143,56 @@ WHERE name IN ('api_extensions');
 	// If API extensions are supported, ensure the list for each cluster member also matches what we expect,
 	// and only return cluster members for whom it does not.
 	apiField := "'[]' as api_extensions"
-	if count == 1 {
+	if hasColumn("api_extensions") {
 		apiField = "api_extensions"
 	}
 
-	stmt = fmt.Sprintf(stmt, apiField, tableName)
+	failureDomainField := "'' as failure_domain"
+	if hasColumn("failure_domain") {
+		failureDomainField = "failure_domain"
+	}
+
+	labelsField := "'{}' as labels"
+	if hasColumn("labels") {
+		labelsField = "labels"
+	}
+
+	heartbeatFailuresField := "0 as heartbeat_failures"
+	if hasColumn("heartbeat_failures") {
+		heartbeatFailuresField = "heartbeat_failures"
+	}
+
+	heartbeatStatusField := "'ONLINE' as heartbeat_status"
+	if hasColumn("heartbeat_status") {
+		heartbeatStatusField = "heartbeat_status"
+	}
+
+	heartbeatPayloadField := "'{}' as heartbeat_payload"
+	if hasColumn("heartbeat_payload") {
+		heartbeatPayloadField = "heartbeat_payload"
+	}
+
+	heartbeatLatencyField := "0 as heartbeat_latency"
+	if hasColumn("heartbeat_latency") {
+		heartbeatLatencyField = "heartbeat_latency"
+	}
+
+	heartbeatErrorField := "'' as heartbeat_error"
+	if hasColumn("heartbeat_error") {
+		heartbeatErrorField = "heartbeat_error"
+	}
+
+	upgradeStatusField := "'' as upgrade_status"
+	if hasColumn("upgrade_status") {
+		upgradeStatusField = "upgrade_status"
+	}
+
+	versionField := "'' as version"
+	if hasColumn("version") {
+		versionField = "version"
+	}
+
+	stmt = fmt.Sprintf(stmt, apiField, failureDomainField, labelsField, heartbeatFailuresField, heartbeatStatusField, heartbeatPayloadField, heartbeatLatencyField, heartbeatErrorField, upgradeStatusField, versionField, tableName)
 	allMembers, err = getCoreClusterMembersRaw(ctx, tx, stmt)
 	if err != nil {
 		return nil, nil, err
@@ -129,7 +203,7 @@ WHERE name IN ('api_extensions');
 		awaitingMembers[member.Name] = member.SchemaInternal < schemaInternal || member.SchemaExternal < schemaExternal
 
 		// If we have API extension support, also compare against the database API extensions.
-		if count == 1 {
+		if hasColumn("api_extensions") {
 			awaitingMembers[member.Name] = member.APIExtensions.IsSameVersion(apiExtensions) != nil || awaitingMembers[member.Name]
 		}
 	}
@@ -0,0 +1,90 @@
+package cluster
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// Code generation directives.
+//
+//go:generate -command mapper lxd-generate db mapper -t config.mapper.go
+//go:generate mapper reset
+//
+//go:generate mapper stmt -e core_config_entry objects table=core_config
+//go:generate mapper stmt -e core_config_entry objects-by-Key table=core_config
+//go:generate mapper stmt -e core_config_entry id table=core_config
+//go:generate mapper stmt -e core_config_entry create table=core_config
+//go:generate mapper stmt -e core_config_entry delete-by-Key table=core_config
+//go:generate mapper stmt -e core_config_entry update table=core_config
+//
+//go:generate mapper method -e core_config_entry ID table=core_config
+//go:generate mapper method -e core_config_entry Exists table=core_config
+//go:generate mapper method -e core_config_entry GetOne table=core_config
+//go:generate mapper method -e core_config_entry GetMany table=core_config
+//go:generate mapper method -e core_config_entry Create table=core_config
+//go:generate mapper method -e core_config_entry DeleteOne-by-Key table=core_config
+//go:generate mapper method -e core_config_entry Update table=core_config
+
+// CoreConfigEntry is the database representation of a cluster-wide configuration key.
+type CoreConfigEntry struct {
+	ID    int
+	Key   string `db:"primary=yes"`
+	Value string
+}
+
+// CoreConfigEntryFilter is the filter struct for filtering results from generated methods.
+type CoreConfigEntryFilter struct {
+	Key *string
+}
+
+// GetCoreConfig returns all cluster-wide configuration keys and their values.
+func GetCoreConfig(ctx context.Context, tx *sql.Tx) (types.ClusterConfig, error) {
+	entries, err := GetCoreConfigEntries(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	config := make(types.ClusterConfig, len(entries))
+	for _, entry := range entries {
+		config[entry.Key] = entry.Value
+	}
+
+	return config, nil
+}
+
+// UpdateCoreConfig applies the given changes to the cluster-wide configuration.
+// Keys with an empty value are removed from the configuration.
+func UpdateCoreConfig(ctx context.Context, tx *sql.Tx, changes types.ClusterConfig) error {
+	for key, value := range changes {
+		if value == "" {
+			err := DeleteCoreConfigEntry(ctx, tx, key)
+			if err != nil && !api.StatusErrorCheck(err, http.StatusNotFound) {
+				return err
+			}
+
+			continue
+		}
+
+		exists, err := CoreConfigEntryExists(ctx, tx, key)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			err = UpdateCoreConfigEntry(ctx, tx, key, CoreConfigEntry{Key: key, Value: value})
+		} else {
+			_, err = CreateCoreConfigEntry(ctx, tx, CoreConfigEntry{Key: key, Value: value})
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -20,6 +20,9 @@ import (
 var extendedCmd = rest.Endpoint{
 	Path: "extended",
 	Post: rest.EndpointAction{Handler: cmdPost, AllowUntrusted: true},
+
+	// The endpoint is only available once every cluster member supports this extension.
+	RequiredExtension: "custom_extension_a_0",
 }
 
 // This is the POST handler for the /1.0/extended endpoint.
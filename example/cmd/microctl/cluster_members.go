@@ -20,6 +20,7 @@ import (
 	"github.com/canonical/microcluster/v3/client"
 	"github.com/canonical/microcluster/v3/cluster"
 	"github.com/canonical/microcluster/v3/microcluster"
+	"github.com/canonical/microcluster/v3/rest/types"
 )
 
 const recoveryConfirmation = `You should only run this command if:
@@ -56,9 +57,21 @@ func (c *cmdClusterMembers) command() *cobra.Command {
 	var cmdRemove = cmdClusterMemberRemove{common: c.common}
 	cmd.AddCommand(cmdRemove.command())
 
+	var cmdRole = cmdClusterMemberRole{common: c.common}
+	cmd.AddCommand(cmdRole.command())
+
+	var cmdRename = cmdClusterMemberRename{common: c.common}
+	cmd.AddCommand(cmdRename.command())
+
+	var cmdAddress = cmdClusterMemberAddress{common: c.common}
+	cmd.AddCommand(cmdAddress.command())
+
 	var cmdList = cmdClusterMembersList{common: c.common}
 	cmd.AddCommand(cmdList.command())
 
+	var cmdHealth = cmdClusterMemberHealth{common: c.common}
+	cmd.AddCommand(cmdHealth.command())
+
 	var cmdRestore = cmdClusterEdit{common: c.common}
 	cmd.AddCommand(cmdRestore.command())
 
@@ -129,13 +142,21 @@ func (c *cmdClusterMembersList) listClusterMembers(ctx context.Context, client *
 	}
 
 	data := make([][]string, len(clusterMembers))
+	versions := map[string]bool{}
 	for i, clusterMember := range clusterMembers {
-		data[i] = []string{clusterMember.Name, clusterMember.Address.String(), clusterMember.Role, shared.CertFingerprint(clusterMember.Certificate.Certificate), string(clusterMember.Status)}
+		data[i] = []string{clusterMember.Name, clusterMember.Address.String(), clusterMember.Role, shared.CertFingerprint(clusterMember.Certificate.Certificate), string(clusterMember.Status), clusterMember.Version}
+		if clusterMember.Version != "" {
+			versions[clusterMember.Version] = true
+		}
 	}
 
-	header := []string{"NAME", "ADDRESS", "ROLE", "FINGERPRINT", "STATUS"}
+	header := []string{"NAME", "ADDRESS", "ROLE", "FINGERPRINT", "STATUS", "VERSION"}
 	sort.Sort(cli.SortColumnsNaturally(data))
 
+	if len(versions) > 1 {
+		fmt.Fprintf(os.Stderr, "Warning: Cluster members are running %d different versions\n", len(versions))
+	}
+
 	return cli.RenderTable(c.flagFormat, header, data, clusterMembers)
 }
 
@@ -156,6 +177,50 @@ func (c *cmdClusterMembersList) listLocalClusterMembers(m *microcluster.MicroClu
 	return cli.RenderTable(c.flagFormat, header, data, members)
 }
 
+type cmdClusterMemberHealth struct {
+	common *CmdControl
+
+	flagFormat string
+}
+
+func (c *cmdClusterMemberHealth) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "health <name>",
+		Short: "Show the heartbeat health of the cluster member with the given name.",
+		RunE:  c.run,
+	}
+
+	cmd.Flags().StringVarP(&c.flagFormat, "format", "f", cli.TableFormatTable, "Format (csv|json|table|yaml|compact)")
+
+	return cmd
+}
+
+func (c *cmdClusterMemberHealth) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 1 {
+		return cmd.Help()
+	}
+
+	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir})
+	if err != nil {
+		return err
+	}
+
+	client, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	health, err := client.GetClusterMemberHealth(cmd.Context(), args[0])
+	if err != nil {
+		return err
+	}
+
+	data := [][]string{{health.Name, string(health.Status), health.LastHeartbeat.String(), health.Latency.String(), strconv.Itoa(health.ConsecutiveMisses), health.LastError}}
+	header := []string{"NAME", "STATUS", "LAST HEARTBEAT", "LATENCY", "MISSES", "LAST ERROR"}
+
+	return cli.RenderTable(c.flagFormat, header, data, health)
+}
+
 type cmdClusterMemberRemove struct {
 	common *CmdControl
 
@@ -197,6 +262,111 @@ func (c *cmdClusterMemberRemove) run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+type cmdClusterMemberRole struct {
+	common *CmdControl
+}
+
+func (c *cmdClusterMemberRole) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "role <name> <voter|stand-by|spare>",
+		Short: "Assign a dqlite role to the cluster member with the given name.",
+		Long: `Assign a dqlite role to the cluster member with the given name.
+
+Roles that dqlite's automatic roles adjustment would immediately undo are refused.
+Roles may still change later if cluster members go offline or join the cluster.`,
+		RunE: c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdClusterMemberRole) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return cmd.Help()
+	}
+
+	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir})
+	if err != nil {
+		return err
+	}
+
+	client, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return client.UpdateClusterMemberRole(cmd.Context(), args[0], args[1])
+}
+
+type cmdClusterMemberRename struct {
+	common *CmdControl
+}
+
+func (c *cmdClusterMemberRename) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "rename <name> <new-name>",
+		Short: "Rename the cluster member with the given name.",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdClusterMemberRename) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return cmd.Help()
+	}
+
+	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir})
+	if err != nil {
+		return err
+	}
+
+	client, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return client.RenameClusterMember(cmd.Context(), args[0], args[1])
+}
+
+type cmdClusterMemberAddress struct {
+	common *CmdControl
+}
+
+func (c *cmdClusterMemberAddress) command() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "set-address <name> <address:port>",
+		Short: "Move the cluster member with the given name to a new address.",
+		RunE:  c.run,
+	}
+
+	return cmd
+}
+
+func (c *cmdClusterMemberAddress) run(cmd *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return cmd.Help()
+	}
+
+	address, err := types.ParseAddrPort(args[1])
+	if err != nil {
+		return err
+	}
+
+	m, err := microcluster.App(microcluster.Args{StateDir: c.common.FlagStateDir})
+	if err != nil {
+		return err
+	}
+
+	client, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return client.UpdateClusterMemberAddress(cmd.Context(), args[0], address)
+}
+
 type cmdClusterEdit struct {
 	common *CmdControl
 }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
 	"time"
 
@@ -14,9 +15,11 @@ import (
 type cmdInit struct {
 	common *CmdControl
 
-	flagBootstrap bool
-	flagToken     string
-	flagConfig    []string
+	flagBootstrap     bool
+	flagToken         string
+	flagConfig        []string
+	flagRestore       string
+	flagFailureDomain string
 }
 
 func (c *cmdInit) command() *cobra.Command {
@@ -25,13 +28,17 @@ func (c *cmdInit) command() *cobra.Command {
 		Short: "Initialize the network endpoint and create or join a new cluster",
 		RunE:  c.run,
 		Example: `  microctl init member1 127.0.0.1:8443 --bootstrap
-    microctl init member1 127.0.0.1:8443 --token <token>`,
+    microctl init member1 127.0.0.1:8443 --token <token>
+    microctl init member1 127.0.0.1:8443 --bootstrap --restore db_backup.tar.gz`,
 	}
 
 	cmd.Flags().BoolVar(&c.flagBootstrap, "bootstrap", false, "Configure a new cluster with this daemon")
 	cmd.Flags().StringVar(&c.flagToken, "token", "", "Join a cluster with a join token")
 	cmd.Flags().StringSliceVar(&c.flagConfig, "config", nil, "Extra configuration to be applied during bootstrap")
+	cmd.Flags().StringVar(&c.flagRestore, "restore", "", "Restore the database from a backup archive or SQL dump when bootstrapping (not a quorum loss recovery tarball)")
+	cmd.Flags().StringVar(&c.flagFailureDomain, "failure-domain", "", "Failure domain of this cluster member, such as its rack or availability zone")
 	cmd.MarkFlagsMutuallyExclusive("bootstrap", "token")
+	cmd.MarkFlagsMutuallyExclusive("restore", "token")
 
 	return cmd
 }
@@ -56,15 +63,28 @@ func (c *cmdInit) run(cmd *cobra.Command, args []string) error {
 		conf[key] = value
 	}
 
+	opts := []microcluster.InitOption{microcluster.WithFailureDomain(c.flagFailureDomain)}
+
 	ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
 	defer cancel()
 
+	if c.flagBootstrap && c.flagRestore != "" {
+		backup, err := os.Open(c.flagRestore)
+		if err != nil {
+			return fmt.Errorf("Failed to open backup: %w", err)
+		}
+
+		defer func() { _ = backup.Close() }()
+
+		return m.RestoreFromBackup(ctx, args[0], args[1], backup, conf, opts...)
+	}
+
 	if c.flagBootstrap {
-		return m.NewCluster(ctx, args[0], args[1], conf)
+		return m.NewCluster(ctx, args[0], args[1], conf, opts...)
 	}
 
 	if c.flagToken != "" {
-		return m.JoinCluster(ctx, args[0], args[1], c.flagToken, conf)
+		return m.JoinCluster(ctx, args[0], args[1], c.flagToken, conf, opts...)
 	}
 
 	return fmt.Errorf("Option must be one of bootstrap or token")
@@ -7,20 +7,38 @@ import (
 	"github.com/olekukonko/tablewriter"
 	"github.com/spf13/cobra"
 
+	"github.com/canonical/microcluster/v3/internal/rest/types"
 	"github.com/canonical/microcluster/v3/microcluster"
 )
 
 type cmdSQL struct {
 	common *CmdControl
+
+	flagAtomic   bool
+	flagReadOnly bool
+	flagArgs     []string
 }
 
 func (c *cmdSQL) command() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "sql <query>",
 		Short: "Execute a SQL query against the daemon",
-		RunE:  c.run,
+		Long: `Execute a SQL query against the daemon
+
+The query can also be one of the following meta-commands:
+  .dump               Dump the database
+  .schema             Dump the database schema
+  .tables             List the tables
+  .indexes [<table>]  List the indexes, optionally only those of a table
+  .count <table>      Count the rows of a table
+  .explain <query>    Show the query plan of a query`,
+		RunE: c.run,
 	}
 
+	cmd.Flags().BoolVar(&c.flagAtomic, "atomic", false, "Run all statements of the query in a single transaction")
+	cmd.Flags().BoolVar(&c.flagReadOnly, "read-only", false, "Prevent the query from modifying the database")
+	cmd.Flags().StringArrayVar(&c.flagArgs, "arg", nil, "Argument for a parameter of the query (can be repeated)")
+
 	return cmd
 }
 
@@ -42,7 +60,19 @@ func (c *cmdSQL) run(cmd *cobra.Command, args []string) error {
 	}
 
 	query := args[0]
-	dump, batch, err := m.SQL(cmd.Context(), query)
+	var dump string
+	var batch *types.SQLBatch
+	if c.flagAtomic || c.flagReadOnly || len(c.flagArgs) > 0 {
+		queryArgs := make([]any, 0, len(c.flagArgs))
+		for _, arg := range c.flagArgs {
+			queryArgs = append(queryArgs, arg)
+		}
+
+		batch, err = m.ExecSQL(cmd.Context(), query, microcluster.SQLOptions{Args: queryArgs, Atomic: c.flagAtomic, ReadOnly: c.flagReadOnly})
+	} else {
+		dump, batch, err = m.SQL(cmd.Context(), query)
+	}
+
 	if err != nil {
 		return err
 	}
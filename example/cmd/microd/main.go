@@ -3,10 +3,12 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"time"
 
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/validate"
 	"github.com/spf13/cobra"
 
 	"github.com/canonical/microcluster/v3/example/api"
@@ -47,6 +49,11 @@ type cmdDaemon struct {
 	flagSocketGroup string
 
 	flagHeartbeatInterval time.Duration
+	flagAutoEvictAfter    time.Duration
+	flagBackupInterval    time.Duration
+	flagSQLWritesUnixOnly bool
+
+	flagSlowTransactionThreshold time.Duration
 }
 
 func (c *cmdDaemon) command() *cobra.Command {
@@ -77,12 +84,25 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 
 		SocketGroup:       c.flagSocketGroup,
 		HeartbeatInterval: c.flagHeartbeatInterval,
+		AutoEvictAfter:    c.flagAutoEvictAfter,
+		BackupInterval:    c.flagBackupInterval,
+		SQLWritesUnixOnly: c.flagSQLWritesUnixOnly,
+
+		SlowTransactionThreshold: c.flagSlowTransactionThreshold,
 
 		ExtensionsSchema: database.SchemaExtensions,
 		APIExtensions:    api.Extensions(),
 		ExtensionServers: api.Servers,
+
+		// Cluster-wide configuration keys can be set through the /core/1.0/config endpoint.
+		ClusterConfigKeys: map[string]func(value string) error{
+			"example.greeting": validate.IsAny,
+			"example.enabled":  validate.Optional(validate.IsBool),
+		},
 	}
 
+	startTime := time.Now()
+
 	// exampleHooks are some example post-action hooks that can be run by MicroCluster.
 	dargs.Hooks = &state.Hooks{
 		// PostBootstrap is run after the daemon is initialized and bootstrapped.
@@ -105,6 +125,16 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 				logger.Warn("Every system should have the 'internal:runtime_extension_v1' extension")
 			}
 
+			// During a rolling upgrade, you can check whether every cluster member supports an extension.
+			clusterHasExt, err := s.ClusterHasExtension(ctx, "custom_extension_a_0")
+			if err != nil {
+				return err
+			}
+
+			if !clusterHasExt {
+				logger.Warn("Not every cluster member has the 'custom_extension_a_0' extension")
+			}
+
 			logger.Info("This is a hook that runs after the daemon is initialized and bootstrapped")
 			logger.Info("Here are the extra configuration keys that were passed into the init --bootstrap command", logCtx)
 
@@ -186,6 +216,21 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 			return nil
 		},
 
+		// OnHeartbeatPayloads is run on the dqlite leader after OnHeartbeat.
+		OnHeartbeatPayloads: func(ctx context.Context, s state.State, payloads map[string]types.HeartbeatPayload) error {
+			// Each cluster member's heartbeat payload holds the data it attached to its last heartbeat response.
+			for name, payload := range payloads {
+				logger.Debugf("Cluster member %s reported uptime %q", name, payload["uptime"])
+			}
+
+			return nil
+		},
+
+		// HeartbeatPayload is run on each cluster member as it responds to a heartbeat, attaching data for the leader to store.
+		HeartbeatPayload: func(ctx context.Context, s state.State) (types.HeartbeatPayload, error) {
+			return types.HeartbeatPayload{"uptime": time.Since(startTime).Round(time.Second).String()}, nil
+		},
+
 		// OnNewMember is run after a new member has joined.
 		OnNewMember: func(ctx context.Context, s state.State, newMember types.ClusterMemberLocal) error {
 			logger.Infof("This is a hook that is run on peer %q when the new cluster member %q has joined", s.Name(), newMember.Name)
@@ -199,6 +244,49 @@ func (c *cmdDaemon) run(cmd *cobra.Command, args []string) error {
 
 			return nil
 		},
+
+		// OnClusterConfigUpdate is run on all cluster members after the cluster-wide config got modified.
+		OnClusterConfigUpdate: func(ctx context.Context, s state.State, config types.ClusterConfig) error {
+			logger.Infof("Running OnClusterConfigUpdate on %q with %d configuration keys", s.Name(), len(config))
+
+			return nil
+		},
+
+		// OnMemberRename is run on all cluster members after a cluster member got renamed.
+		OnMemberRename: func(ctx context.Context, s state.State, oldName string, newName string) error {
+			logger.Infof("Running OnMemberRename on %q after %q was renamed to %q", s.Name(), oldName, newName)
+
+			return nil
+		},
+
+		// OnMemberDown is run on all reachable cluster members after a cluster member stopped responding to heartbeats.
+		OnMemberDown: func(ctx context.Context, s state.State, member types.ClusterMember) error {
+			logger.Infof("Running OnMemberDown on %q after %q became %s", s.Name(), member.Name, member.HeartbeatStatus)
+
+			return nil
+		},
+
+		// OnMemberUp is run on all reachable cluster members after a cluster member responds to heartbeats again.
+		OnMemberUp: func(ctx context.Context, s state.State, member types.ClusterMember) error {
+			logger.Infof("Running OnMemberUp on %q after %q came back online", s.Name(), member.Name)
+
+			return nil
+		},
+
+		// OnUpgradeRequired is run on a cluster member once it finds out it is behind the rest of the cluster.
+		// Returning an error reports that no upgrade was started, so the hook runs again on a later heartbeat.
+		OnUpgradeRequired: func(ctx context.Context, s state.State) error {
+			logger.Warnf("Cluster member %q needs to be upgraded to match the rest of the cluster", s.Name())
+
+			return fmt.Errorf("This example does not upgrade itself")
+		},
+
+		// OnMemberEvicted is run on all reachable cluster members after an offline cluster member was automatically evicted.
+		OnMemberEvicted: func(ctx context.Context, s state.State, member types.ClusterMember) error {
+			logger.Infof("Running OnMemberEvicted on %q after %q was evicted, last heartbeat at %s", s.Name(), member.Name, member.LastHeartbeat)
+
+			return nil
+		},
 	}
 
 	return m.Start(cmd.Context(), dargs)
@@ -219,6 +307,10 @@ func main() {
 	app.PersistentFlags().StringVar(&daemonCmd.flagSocketGroup, "socket-group", "", "Group to set socket's group ownership to")
 
 	app.PersistentFlags().DurationVar(&daemonCmd.flagHeartbeatInterval, "heartbeat", time.Second*10, "Time between attempted heartbeats")
+	app.PersistentFlags().DurationVar(&daemonCmd.flagAutoEvictAfter, "auto-evict-after", 0, "Time after which an offline cluster member is automatically removed (disabled if 0)")
+	app.PersistentFlags().DurationVar(&daemonCmd.flagBackupInterval, "backup-interval", 0, "Interval between scheduled database backups taken by the leader (disabled if 0)")
+	app.PersistentFlags().BoolVar(&daemonCmd.flagSQLWritesUnixOnly, "sql-writes-unix-only", false, "Only allow SQL statements that modify the database over the unix socket")
+	app.PersistentFlags().DurationVar(&daemonCmd.flagSlowTransactionThreshold, "slow-transaction-threshold", 0, "Duration above which database transactions are logged as slow (disabled if 0)")
 
 	app.SetVersionTemplate("{{.Version}}\n")
 
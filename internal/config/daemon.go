@@ -94,6 +94,14 @@ func (d *DaemonConfig) GetAddress() types.AddrPort {
 	return d.config.Address
 }
 
+// GetFailureDomain returns the daemon's failure domain.
+func (d *DaemonConfig) GetFailureDomain() string {
+	d.lock.RLock()
+	defer d.lock.RUnlock()
+
+	return d.config.FailureDomain
+}
+
 // GetServers returns the daemon's additional listener configs.
 func (d *DaemonConfig) GetServers() map[string]types.ServerConfig {
 	d.lock.RLock()
@@ -124,6 +132,14 @@ func (d *DaemonConfig) SetAddress(address types.AddrPort) {
 	d.config.Address = address
 }
 
+// SetFailureDomain sets the daemon's failure domain.
+func (d *DaemonConfig) SetFailureDomain(failureDomain string) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.config.FailureDomain = failureDomain
+}
+
 // SetServers sets the daemon's additional listener configs.
 func (d *DaemonConfig) SetServers(servers map[string]types.ServerConfig) {
 	d.lock.Lock()
@@ -2,6 +2,7 @@ package daemon
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"io"
 	"net/http"
@@ -25,6 +26,7 @@ import (
 	"github.com/canonical/microcluster/v3/cluster"
 	internalConfig "github.com/canonical/microcluster/v3/internal/config"
 	"github.com/canonical/microcluster/v3/internal/db"
+	"github.com/canonical/microcluster/v3/internal/db/update"
 	"github.com/canonical/microcluster/v3/internal/endpoints"
 	"github.com/canonical/microcluster/v3/internal/extensions"
 	"github.com/canonical/microcluster/v3/internal/recover"
@@ -58,6 +60,45 @@ type Args struct {
 	// How often heartbeats are attempted
 	HeartbeatInterval time.Duration
 
+	// Number of consecutive missed heartbeats after which a cluster member is considered suspect.
+	HeartbeatSuspectThreshold int
+
+	// Number of consecutive missed heartbeats after which a cluster member is considered offline.
+	HeartbeatOfflineThreshold int
+
+	// Time a cluster member must remain offline before the leader automatically evicts it from the cluster.
+	// Automatic eviction is disabled if unset.
+	AutoEvictAfter time.Duration
+
+	// Minimum number of online voters that must remain in the cluster for an automatic eviction to take place.
+	AutoEvictMinVoters int
+
+	// How often the dqlite leader takes a backup of the database into the backups directory of its state directory.
+	// Backups stay on the cluster member that took them, so after a change of leader they are spread across members,
+	// which each apply the retention rules to their own backups. Scheduled backups are disabled if unset.
+	BackupInterval time.Duration
+
+	// Number of most recent scheduled backups each cluster member keeps. Defaults to 7, or no limit if negative.
+	BackupKeepLast int
+
+	// Age after which scheduled backups are removed. Backups are not removed based on their age if unset.
+	BackupMaxAge time.Duration
+
+	// Number of read-only database transactions that can run concurrently. Defaults to 4.
+	DatabaseReadConns int
+
+	// Duration above which database transactions are logged as slow, along with their caller.
+	// Slow transactions are not logged if unset.
+	SlowTransactionThreshold time.Duration
+
+	// Only allow SQL statements that modify the database to be run over the unix socket.
+	// Requests from other systems to the SQL endpoint can then only run SELECT, EXPLAIN, and VALUES statements.
+	SQLWritesUnixOnly bool
+
+	// Tables whose changes can be watched with Watch or the database changes endpoint. The triggers recording their
+	// changes are created when schema updates are applied, so a table must be added here along with a schema update.
+	WatchedTables []string
+
 	// List of schema updates in the order that they should be applied.
 	ExtensionsSchema []schema.Update
 
@@ -69,6 +110,10 @@ type Args struct {
 
 	// Each rest.Server will be initialized and managed by microcluster.
 	ExtensionServers map[string]rest.Server
+
+	// ClusterConfigKeys maps each supported cluster-wide configuration key to a function validating its value,
+	// such as those found in github.com/canonical/lxd/shared/validate.
+	ClusterConfigKeys map[string]func(value string) error
 }
 
 // Daemon holds information for the microcluster daemon.
@@ -99,6 +144,23 @@ type Daemon struct {
 
 	Extensions extensions.Extensions // Extensions supported at runtime by the daemon.
 
+	clusterConfigKeys map[string]func(value string) error // Validators for the supported cluster-wide configuration keys.
+
+	heartbeatSuspectThreshold int // Consecutive missed heartbeats after which a cluster member is suspect.
+	heartbeatOfflineThreshold int // Consecutive missed heartbeats after which a cluster member is offline.
+
+	autoEvictAfter     time.Duration // Time after which an offline cluster member is evicted, or zero if disabled.
+	autoEvictMinVoters int           // Online voters that must remain for an automatic eviction to take place.
+
+	backupInterval time.Duration // Interval between scheduled backups, or zero if disabled.
+	backupKeepLast int           // Number of most recent scheduled backups to keep, or no limit if negative.
+	backupMaxAge   time.Duration // Age after which scheduled backups are removed, or zero if disabled.
+
+	databaseReadConns        int           // Number of read-only database transactions that can run concurrently.
+	slowTransactionThreshold time.Duration // Duration above which transactions are logged as slow, or zero if disabled.
+	sqlWritesUnixOnly        bool          // Whether SQL statements that modify the database can only be run over the unix socket.
+	watchedTables            []string      // Tables whose changes can be watched.
+
 	// stop is a sync.Once which wraps the daemon's stop sequence. Each call will block until the first one completes.
 	stop func() error
 
@@ -210,6 +272,63 @@ func (d *Daemon) Run(ctx context.Context, stateDir string, args Args) error {
 
 	d.extensionServersMu.Unlock()
 
+	d.clusterConfigKeys = make(map[string]func(value string) error, len(args.ClusterConfigKeys))
+	for k, v := range args.ClusterConfigKeys {
+		d.clusterConfigKeys[k] = v
+	}
+
+	d.heartbeatSuspectThreshold = args.HeartbeatSuspectThreshold
+	if d.heartbeatSuspectThreshold == 0 {
+		d.heartbeatSuspectThreshold = db.DefaultHeartbeatSuspectThreshold
+	}
+
+	d.heartbeatOfflineThreshold = args.HeartbeatOfflineThreshold
+	if d.heartbeatOfflineThreshold == 0 {
+		d.heartbeatOfflineThreshold = db.DefaultHeartbeatOfflineThreshold
+	}
+
+	if d.heartbeatSuspectThreshold < 0 || d.heartbeatOfflineThreshold < d.heartbeatSuspectThreshold {
+		return fmt.Errorf("Invalid heartbeat thresholds, the offline threshold must be at least the suspect threshold")
+	}
+
+	d.autoEvictAfter = args.AutoEvictAfter
+	d.autoEvictMinVoters = args.AutoEvictMinVoters
+	if d.autoEvictMinVoters == 0 {
+		d.autoEvictMinVoters = db.DefaultAutoEvictMinVoters
+	}
+
+	if d.autoEvictAfter < 0 || d.autoEvictMinVoters < 0 {
+		return fmt.Errorf("Invalid automatic eviction policy, the threshold and minimum voter count cannot be negative")
+	}
+
+	d.backupInterval = args.BackupInterval
+	d.backupMaxAge = args.BackupMaxAge
+	d.backupKeepLast = args.BackupKeepLast
+	if d.backupKeepLast == 0 {
+		d.backupKeepLast = db.DefaultBackupKeepLast
+	}
+
+	if d.backupInterval < 0 || d.backupMaxAge < 0 {
+		return fmt.Errorf("Invalid backup schedule, the interval and maximum age cannot be negative")
+	}
+
+	d.databaseReadConns = args.DatabaseReadConns
+	if d.databaseReadConns == 0 {
+		d.databaseReadConns = db.DefaultReadConns
+	}
+
+	if d.databaseReadConns < 0 {
+		return fmt.Errorf("Invalid number of concurrent read-only database transactions, it cannot be negative")
+	}
+
+	d.slowTransactionThreshold = args.SlowTransactionThreshold
+	if d.slowTransactionThreshold < 0 {
+		return fmt.Errorf("Invalid slow transaction threshold, it cannot be negative")
+	}
+
+	d.sqlWritesUnixOnly = args.SQLWritesUnixOnly
+	d.watchedTables = args.WatchedTables
+
 	err = d.init(args.PreInitListenAddress, args.SocketGroup, args.HeartbeatInterval, args.ExtensionsSchema, args.APIExtensions, args.Hooks)
 	if err != nil {
 		return fmt.Errorf("Daemon failed to start: %w", err)
@@ -268,6 +387,26 @@ func (d *Daemon) init(listenAddress string, socketGroup string, heartbeatInterva
 	}
 
 	d.db = db.NewDB(d.shutdownCtx, d.ServerCert, d.ClusterCert, d.Name, d.os, heartbeatInterval)
+	d.db.SetReadConns(d.databaseReadConns)
+	d.db.SetSlowTransactionThreshold(d.slowTransactionThreshold)
+
+	// Only a hook provided by the consumer can start an upgrade, unlike the default no-op hook.
+	if hooks != nil && hooks.OnUpgradeRequired != nil {
+		d.db.SetUpgradeRequiredHook(func(ctx context.Context) error {
+			return d.hooks.OnUpgradeRequired(ctx, d.State())
+		})
+	}
+
+	d.db.SetChangeHook(func(ctx context.Context) error {
+		cluster, err := d.State().Cluster(false)
+		if err != nil {
+			return err
+		}
+
+		return cluster.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
+			return internalClient.NotifyDatabaseChanges(ctx, &c.Client)
+		})
+	})
 
 	listenAddr := api.NewURL()
 	if listenAddress != "" {
@@ -324,6 +463,7 @@ func (d *Daemon) init(listenAddress string, socketGroup string, heartbeatInterva
 	}
 
 	d.db.SetSchema(schemaExtensions, d.Extensions)
+	d.db.Schema().WatchTables(d.watchedTables)
 
 	err = d.reloadIfBootstrapped()
 	if err != nil {
@@ -347,8 +487,16 @@ func (d *Daemon) applyHooks(hooks *state.Hooks) {
 		return nil
 	}
 	noOpConfigHook := func(ctx context.Context, s state.State, config types.DaemonConfig) error { return nil }
+	noOpClusterConfigHook := func(ctx context.Context, s state.State, config types.ClusterConfig) error { return nil }
 	noOpNewMemberHook := func(ctx context.Context, s state.State, newMember types.ClusterMemberLocal) error { return nil }
 	noOpHeartbeatHook := func(ctx context.Context, s state.State, roleStatus map[string]types.RoleStatus) error { return nil }
+	noOpHeartbeatPayloadsHook := func(ctx context.Context, s state.State, payloads map[string]types.HeartbeatPayload) error {
+		return nil
+	}
+
+	noOpHeartbeatPayloadHook := func(ctx context.Context, s state.State) (types.HeartbeatPayload, error) { return nil, nil }
+	noOpRenameHook := func(ctx context.Context, s state.State, oldName string, newName string) error { return nil }
+	noOpMemberStatusHook := func(ctx context.Context, s state.State, member types.ClusterMember) error { return nil }
 
 	if hooks == nil {
 		d.hooks = state.Hooks{}
@@ -380,6 +528,14 @@ func (d *Daemon) applyHooks(hooks *state.Hooks) {
 		d.hooks.OnHeartbeat = noOpHeartbeatHook
 	}
 
+	if d.hooks.OnHeartbeatPayloads == nil {
+		d.hooks.OnHeartbeatPayloads = noOpHeartbeatPayloadsHook
+	}
+
+	if d.hooks.HeartbeatPayload == nil {
+		d.hooks.HeartbeatPayload = noOpHeartbeatPayloadHook
+	}
+
 	if d.hooks.OnNewMember == nil {
 		d.hooks.OnNewMember = noOpNewMemberHook
 	}
@@ -395,6 +551,30 @@ func (d *Daemon) applyHooks(hooks *state.Hooks) {
 	if d.hooks.OnDaemonConfigUpdate == nil {
 		d.hooks.OnDaemonConfigUpdate = noOpConfigHook
 	}
+
+	if d.hooks.OnClusterConfigUpdate == nil {
+		d.hooks.OnClusterConfigUpdate = noOpClusterConfigHook
+	}
+
+	if d.hooks.OnMemberRename == nil {
+		d.hooks.OnMemberRename = noOpRenameHook
+	}
+
+	if d.hooks.OnMemberDown == nil {
+		d.hooks.OnMemberDown = noOpMemberStatusHook
+	}
+
+	if d.hooks.OnMemberUp == nil {
+		d.hooks.OnMemberUp = noOpMemberStatusHook
+	}
+
+	if d.hooks.OnUpgradeRequired == nil {
+		d.hooks.OnUpgradeRequired = noOpHook
+	}
+
+	if d.hooks.OnMemberEvicted == nil {
+		d.hooks.OnMemberEvicted = noOpMemberStatusHook
+	}
 }
 
 func (d *Daemon) reloadIfBootstrapped() error {
@@ -563,11 +743,14 @@ func (d *Daemon) StartAPI(ctx context.Context, bootstrap bool, initConfig map[st
 	// If bootstrapping the first node, just open the database and create an entry for ourselves.
 	if bootstrap {
 		clusterMember := cluster.CoreClusterMember{
-			Name:        localNode.Name,
-			Address:     localNode.Address.String(),
-			Certificate: localNode.Certificate.String(),
-			Heartbeat:   time.Time{},
-			Role:        cluster.Pending,
+			Name:            localNode.Name,
+			Address:         localNode.Address.String(),
+			Certificate:     localNode.Certificate.String(),
+			Heartbeat:       time.Time{},
+			Role:            cluster.Pending,
+			FailureDomain:   d.config.GetFailureDomain(),
+			HeartbeatStatus: types.MemberOnline,
+			Version:         d.version,
 		}
 
 		clusterMember.SchemaInternal, clusterMember.SchemaExternal, _ = d.db.Schema().Version()
@@ -605,6 +788,14 @@ func (d *Daemon) StartAPI(ctx context.Context, bootstrap bool, initConfig map[st
 		}
 	}
 
+	// Record the version we are now running, in case it changed since we last started.
+	err = d.db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return update.UpdateClusterMemberVersion(ctx, tx, d.version, localNode.Name)
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to record cluster member version: %w", err)
+	}
+
 	err = d.trustStore.Refresh()
 	if err != nil {
 		return err
@@ -1004,6 +1195,67 @@ func (d *Daemon) ReloadCert(name types.CertificateName) error {
 	return nil
 }
 
+// UpdateAddress moves the core API listener and dqlite to the given address, and records it in the daemon configuration.
+func (d *Daemon) UpdateAddress(address types.AddrPort) error {
+	d.extensionServersMu.RLock()
+	err := resources.ValidateEndpoints(d.extensionServers, address.String())
+	d.extensionServersMu.RUnlock()
+	if err != nil {
+		return err
+	}
+
+	url := api.NewURL().Scheme("https").Host(address.String())
+
+	err = d.endpoints.DownByName(endpoints.EndpointsCore)
+	if err != nil {
+		return err
+	}
+
+	serverEndpoints := []rest.Resources{resources.InternalEndpoints, resources.PublicEndpoints}
+	err = d.addCoreServers(false, *url, d.ClusterCert(), serverEndpoints)
+	if err != nil {
+		return err
+	}
+
+	d.config.SetAddress(address)
+	err = d.config.Write()
+	if err != nil {
+		return err
+	}
+
+	return d.db.UpdateAddress(d.Extensions, d.project, *url)
+}
+
+// ReplaceServerCert writes the given keypair as the server certificate and loads it in place of the current one.
+// Unlike ReloadCert, this is allowed after initialization as it does not touch the core API listeners,
+// which are served with the cluster certificate by then.
+func (d *Daemon) ReplaceServerCert(cert []byte, key []byte) error {
+	d.clusterMu.Lock()
+	defer d.clusterMu.Unlock()
+
+	certPath := filepath.Join(d.os.StateDir, "server.crt")
+	keyPath := filepath.Join(d.os.StateDir, "server.key")
+
+	err := os.WriteFile(keyPath, key, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to write server key: %w", err)
+	}
+
+	err = os.WriteFile(certPath, cert, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to write server certificate: %w", err)
+	}
+
+	serverCert, err := util.LoadServerCert(d.os.StateDir)
+	if err != nil {
+		return err
+	}
+
+	d.serverCert = serverCert
+
+	return nil
+}
+
 // ServerCert ensures both the daemon and state have the same server cert.
 func (d *Daemon) ServerCert() *shared.CertInfo {
 	d.clusterMu.RLock()
@@ -1062,25 +1314,36 @@ func (d *Daemon) FileSystem() *sys.OS {
 // State creates a State instance with the daemon's stateful components.
 func (d *Daemon) State() state.State {
 	state := &internalState.InternalState{
-		Hooks:                    &d.hooks,
-		Context:                  d.shutdownCtx,
-		ReadyCh:                  d.ReadyChan,
-		SetConfig:                d.setConfig,
-		StartAPI:                 d.StartAPI,
-		Extensions:               d.Extensions,
-		Endpoints:                d.endpoints,
-		UpdateServers:            d.UpdateServers,
-		LocalConfig:              d.LocalConfig,
-		ReloadCert:               d.ReloadCert,
-		InternalFileSystem:       d.FileSystem,
-		InternalAddress:          d.Address,
-		InternalName:             d.Name,
-		InternalVersion:          d.Version,
-		InternalServerCert:       d.ServerCert,
-		InternalClusterCert:      d.ClusterCert,
-		InternalDatabase:         d.db,
-		InternalRemotes:          d.trustStore.Remotes,
-		InternalExtensionServers: d.ExtensionServers,
+		Hooks:                     &d.hooks,
+		Context:                   d.shutdownCtx,
+		ReadyCh:                   d.ReadyChan,
+		SetConfig:                 d.setConfig,
+		StartAPI:                  d.StartAPI,
+		Extensions:                d.Extensions,
+		ClusterConfigKeys:         d.clusterConfigKeys,
+		HeartbeatSuspectThreshold: d.heartbeatSuspectThreshold,
+		HeartbeatOfflineThreshold: d.heartbeatOfflineThreshold,
+		AutoEvictAfter:            d.autoEvictAfter,
+		AutoEvictMinVoters:        d.autoEvictMinVoters,
+		BackupInterval:            d.backupInterval,
+		BackupKeepLast:            d.backupKeepLast,
+		BackupMaxAge:              d.backupMaxAge,
+		SQLWritesUnixOnly:         d.sqlWritesUnixOnly,
+		Endpoints:                 d.endpoints,
+		UpdateServers:             d.UpdateServers,
+		LocalConfig:               d.LocalConfig,
+		ReloadCert:                d.ReloadCert,
+		ReplaceServerCert:         d.ReplaceServerCert,
+		UpdateAddress:             d.UpdateAddress,
+		InternalFileSystem:        d.FileSystem,
+		InternalAddress:           d.Address,
+		InternalName:              d.Name,
+		InternalVersion:           d.Version,
+		InternalServerCert:        d.ServerCert,
+		InternalClusterCert:       d.ClusterCert,
+		InternalDatabase:          d.db,
+		InternalRemotes:           d.trustStore.Remotes,
+		InternalExtensionServers:  d.ExtensionServers,
 		Stop: func() (exit func(), stopErr error) {
 			stopErr = d.stop()
 			exit = func() {
@@ -0,0 +1,626 @@
+package db
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/logger"
+	"gopkg.in/yaml.v3"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/db/update"
+	"github.com/canonical/microcluster/v3/internal/extensions"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+const (
+	// BackupMetadataFile is the name of the file holding the backup metadata in a backup archive.
+	BackupMetadataFile = "metadata.yaml"
+
+	// BackupDumpFile is the name of the file holding the SQL dump of the database in a backup archive.
+	BackupDumpFile = "database.sql"
+)
+
+// backupChunkSize is the size of the chunks in which the SQL dump is written to a backup archive.
+const backupChunkSize = 64 * 1024
+
+// maxUpgradeSnapshots is the number of most recent snapshots kept in the upgrade snapshots directory.
+const maxUpgradeSnapshots = 3
+
+// backupTimeFormat is the format of the creation time in the name of a backup archive.
+// tar interprets `:` as a remote drive, so use the ISO8601 basic format without colons.
+const backupTimeFormat = "2006-01-02T150405Z0700"
+
+// BackupFileName returns the name of a backup archive created at the given time.
+func BackupFileName(createdAt time.Time) string {
+	return fmt.Sprintf("db_backup.%s.tar.gz", createdAt.Format(backupTimeFormat))
+}
+
+// backupFileTime returns the creation time recorded in the name of a backup archive by BackupFileName, and whether the
+// name is that of a backup archive at all.
+func backupFileTime(name string) (time.Time, bool) {
+	timestamp, ok := strings.CutPrefix(name, "db_backup.")
+	if !ok {
+		return time.Time{}, false
+	}
+
+	timestamp, ok = strings.CutSuffix(timestamp, ".tar.gz")
+	if !ok {
+		return time.Time{}, false
+	}
+
+	createdAt, err := time.Parse(backupTimeFormat, timestamp)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return createdAt.UTC(), true
+}
+
+// Backup is a consistent copy of the database, along with metadata describing it.
+type Backup struct {
+	Metadata types.DatabaseBackup
+	Dump     string
+}
+
+// Backup takes a consistent SQL dump of the live database in a single read-only transaction, along with the list of
+// cluster members and schema information at that point in time. The given version is recorded in the backup metadata.
+// The dump is held in memory in full, so taking a backup requires at least as much memory as the size of the database.
+func (db *DqliteDB) Backup(ctx context.Context, version string) (*Backup, error) {
+	schemaInternal, schemaExternal, apiExtensions := db.SchemaVersion()
+	backup := &Backup{
+		Metadata: types.DatabaseBackup{
+			CreatedAt:             time.Now().UTC(),
+			Name:                  db.memberName(),
+			Version:               version,
+			SchemaInternalVersion: schemaInternal,
+			SchemaExternalVersion: schemaExternal,
+			APIExtensions:         apiExtensions,
+		},
+	}
+
+	err := db.ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		clusterMembers, err := cluster.GetCoreClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		backup.Metadata.Members = make([]types.DatabaseBackupMember, 0, len(clusterMembers))
+		for _, clusterMember := range clusterMembers {
+			address, err := types.ParseAddrPort(clusterMember.Address)
+			if err != nil {
+				return fmt.Errorf("Failed to parse address %q of cluster member %q: %w", clusterMember.Address, clusterMember.Name, err)
+			}
+
+			backup.Metadata.Members = append(backup.Metadata.Members, types.DatabaseBackupMember{
+				Name:    clusterMember.Name,
+				Address: address,
+				Role:    string(clusterMember.Role),
+			})
+		}
+
+		backup.Dump, err = query.Dump(ctx, tx, false)
+		if err != nil {
+			return fmt.Errorf("Failed to dump database: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return backup, nil
+}
+
+// WriteArchive writes the backup to the given writer as a gzip compressed tarball containing the backup metadata and
+// the SQL dump of the database.
+func (b *Backup) WriteArchive(w io.Writer) error {
+	metadata, err := yaml.Marshal(b.Metadata)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal backup metadata: %w", err)
+	}
+
+	gzWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	files := []struct {
+		name string
+		data string
+	}{
+		{name: BackupMetadataFile, data: string(metadata)},
+		{name: BackupDumpFile, data: b.Dump},
+	}
+
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    file.name,
+			Mode:    0600,
+			Size:    int64(len(file.data)),
+			ModTime: b.Metadata.CreatedAt,
+		}
+
+		err := tarWriter.WriteHeader(header)
+		if err != nil {
+			return fmt.Errorf("Failed to write tar header for %q: %w", file.name, err)
+		}
+
+		// Write the data in chunks rather than copying the whole dump at once.
+		for data := file.data; len(data) > 0; {
+			chunk := data[:min(len(data), backupChunkSize)]
+			data = data[len(chunk):]
+
+			_, err = tarWriter.Write([]byte(chunk))
+			if err != nil {
+				return fmt.Errorf("Failed to write %q to backup archive: %w", file.name, err)
+			}
+		}
+	}
+
+	err = tarWriter.Close()
+	if err != nil {
+		return fmt.Errorf("Failed to close tar writer: %w", err)
+	}
+
+	err = gzWriter.Close()
+	if err != nil {
+		return fmt.Errorf("Failed to close gzip writer: %w", err)
+	}
+
+	return nil
+}
+
+// ReadBackup reads a backup from either a gzip compressed tarball written by Backup.WriteArchive, or a plain SQL dump
+// of the database such as the one returned by `GET /core/internal/sql`. A plain SQL dump carries no metadata.
+// Tarballs of the dqlite directory written by recover.CreateDatabaseBackup are not supported, and return an error.
+func ReadBackup(r io.Reader) (*Backup, error) {
+	reader := bufio.NewReader(r)
+	magic, err := reader.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("Failed to read backup: %w", err)
+	}
+
+	// Treat anything that is not gzip compressed as a plain SQL dump.
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		dump, err := io.ReadAll(reader)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read backup: %w", err)
+		}
+
+		if strings.TrimSpace(string(dump)) == "" {
+			return nil, fmt.Errorf("Backup is empty")
+		}
+
+		return &Backup{Dump: string(dump)}, nil
+	}
+
+	gzReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to decompress backup: %w", err)
+	}
+
+	backup := &Backup{}
+	var hasDatabaseDir bool
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read backup archive: %w", err)
+		}
+
+		switch filepath.Clean(header.Name) {
+		case BackupMetadataFile:
+			metadata, err := io.ReadAll(tarReader)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read %q from backup archive: %w", header.Name, err)
+			}
+
+			err = yaml.Unmarshal(metadata, &backup.Metadata)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to parse backup metadata: %w", err)
+			}
+		case BackupDumpFile:
+			dump, err := io.ReadAll(tarReader)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to read %q from backup archive: %w", header.Name, err)
+			}
+
+			backup.Dump = string(dump)
+		default:
+			// Archives written by recover.CreateDatabaseBackup hold a copy of the dqlite directory.
+			if strings.HasPrefix(filepath.Clean(header.Name), "database") {
+				hasDatabaseDir = true
+			}
+		}
+	}
+
+	if backup.Dump == "" && hasDatabaseDir {
+		return nil, fmt.Errorf("Archive holds a copy of the dqlite directory rather than a SQL dump, it can only be restored by extracting it into the state directory of the cluster member that created it")
+	}
+
+	if backup.Dump == "" {
+		return nil, fmt.Errorf("Archive does not contain %q", BackupDumpFile)
+	}
+
+	return backup, nil
+}
+
+// SetRestore sets a SQL dump to load into the database when it is next bootstrapped.
+func (db *DqliteDB) SetRestore(dump string) {
+	db.restoreDump = dump
+}
+
+// restore loads the SQL dump set with SetRestore into the empty database, and rewrites the list of cluster members so
+// that only this cluster member remains. Schema updates are applied to the restored database afterwards as usual.
+func (db *DqliteDB) restore(ctx context.Context, ext extensions.Extensions) error {
+	dump := db.restoreDump
+	db.restoreDump = ""
+
+	err := db.loadDump(ctx, dump, ext)
+	if err != nil {
+		return fmt.Errorf("Failed to restore database: %w", err)
+	}
+
+	return nil
+}
+
+// loadDump loads the given SQL dump in a single transaction. Tables are dumped in the order they were created, so a
+// table recreated by a schema update may come after the tables referencing it. As with schema updates, foreign keys
+// are disabled while loading the dump, and only checked once all tables are loaded.
+func (db *DqliteDB) loadDump(ctx context.Context, dump string, ext extensions.Extensions) error {
+	// The pragma has no effect within a transaction, and applies to the whole connection, so use a dedicated one.
+	conn, err := db.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to get database connection: %w", err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys=OFF")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_, err := conn.ExecContext(context.Background(), "PRAGMA foreign_keys=ON")
+		if err != nil {
+			logger.Error("Failed to re-enable foreign keys after loading database backup, discarding connection", logger.Ctx{"error": err})
+
+			// Returning driver.ErrBadConn removes the connection from the pool instead of returning it without foreign keys.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to begin transaction: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(ctx, dumpStatements(dump))
+	if err != nil {
+		return fmt.Errorf("Failed to load database backup: %w", err)
+	}
+
+	schemaInternal, schemaExternal, _ := db.schema.Version()
+	err = update.RestoreClusterMembers(ctx, tx, schemaInternal, schemaExternal, ext, db.memberName())
+	if err != nil {
+		return err
+	}
+
+	err = checkForeignKeys(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// checkForeignKeys returns an error if any row of the database references a row that does not exist.
+func checkForeignKeys(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return fmt.Errorf("Failed to check foreign keys: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	if rows.Next() {
+		var table string
+		var rowID sql.NullInt64
+		var parent string
+		var fkID int64
+		err := rows.Scan(&table, &rowID, &parent, &fkID)
+		if err != nil {
+			return fmt.Errorf("Failed to scan foreign key violation: %w", err)
+		}
+
+		return fmt.Errorf("Row %d of table %q references a missing row of table %q", rowID.Int64, table, parent)
+	}
+
+	return rows.Err()
+}
+
+// dumpStatements strips the statements managing the transaction and foreign keys from a SQL dump, so that it can be
+// loaded in a single transaction.
+func dumpStatements(dump string) string {
+	var builder strings.Builder
+	for _, line := range strings.Split(dump, "\n") {
+		switch strings.TrimSpace(line) {
+		case "PRAGMA foreign_keys=OFF;", "BEGIN TRANSACTION;", "COMMIT;":
+			continue
+		}
+
+		builder.WriteString(line + "\n")
+	}
+
+	return builder.String()
+}
+
+// BackupStatus returns the time and error of the last attempt at a scheduled backup by this cluster member.
+func (db *DqliteDB) BackupStatus() (lastAttempt time.Time, lastError string) {
+	db.backupLock.Lock()
+	defer db.backupLock.Unlock()
+
+	return db.lastBackupAttempt, db.lastBackupError
+}
+
+// ScheduledBackup writes a backup archive of the database to the backups directory if the most recent one there is at
+// least interval old, and then removes the backups that fall outside of the retention rules.
+// The given version is recorded in the backup metadata.
+//
+// Backups are stored on the cluster member that took them, so the backups directory only holds those taken while this
+// cluster member was the dqlite leader.
+func (db *DqliteDB) ScheduledBackup(ctx context.Context, version string, interval time.Duration, keepLast int, maxAge time.Duration) error {
+	backups, err := ListBackups(db.os.BackupsDir)
+	if err != nil {
+		return err
+	}
+
+	if len(backups) > 0 && time.Since(backups[len(backups)-1].CreatedAt) < interval {
+		return nil
+	}
+
+	err = db.writeBackupFile(ctx, version)
+
+	db.backupLock.Lock()
+	db.lastBackupAttempt = time.Now().UTC()
+	db.lastBackupError = ""
+	if err != nil {
+		db.lastBackupError = err.Error()
+	}
+
+	db.backupLock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return db.PruneBackups(keepLast, maxAge)
+}
+
+// PruneBackups removes the scheduled backups stored on this cluster member that fall outside of the retention rules.
+// As backups are only taken by the dqlite leader, every cluster member prunes its own backups, so that those taken
+// before a change of leader are removed as well.
+func (db *DqliteDB) PruneBackups(keepLast int, maxAge time.Duration) error {
+	backups, err := ListBackups(db.os.BackupsDir)
+	if err != nil {
+		return err
+	}
+
+	for _, backup := range expiredBackups(backups, keepLast, maxAge, time.Now()) {
+		logger.Info("Removing expired database backup", logger.Ctx{"name": backup.Name})
+		err := os.Remove(filepath.Join(db.os.BackupsDir, backup.Name))
+		if err != nil {
+			return fmt.Errorf("Failed to remove expired backup %q: %w", backup.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// writeBackupFile takes a backup of the database and writes it to the backups directory.
+func (db *DqliteDB) writeBackupFile(ctx context.Context, version string) error {
+	backup, err := db.Backup(ctx, version)
+	if err != nil {
+		return err
+	}
+
+	backupPath, err := backup.writeFile(db.os.BackupsDir)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Created database backup", logger.Ctx{"archive": backupPath})
+
+	return nil
+}
+
+// writeFile writes the backup archive to the given directory, and returns its path.
+// The archive is written to a temporary file first, so that partial backups are never listed.
+func (b *Backup) writeFile(dir string) (string, error) {
+	err := os.MkdirAll(dir, 0700)
+	if err != nil {
+		return "", fmt.Errorf("Failed to create backups directory: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(dir, ".db_backup.*")
+	if err != nil {
+		return "", fmt.Errorf("Failed to create backup file: %w", err)
+	}
+
+	defer func() { _ = os.Remove(tmpFile.Name()) }()
+
+	err = b.WriteArchive(tmpFile)
+	if err != nil {
+		_ = tmpFile.Close()
+		return "", err
+	}
+
+	err = tmpFile.Close()
+	if err != nil {
+		return "", fmt.Errorf("Failed to close backup file: %w", err)
+	}
+
+	backupPath := filepath.Join(dir, BackupFileName(b.Metadata.CreatedAt))
+	err = os.Rename(tmpFile.Name(), backupPath)
+	if err != nil {
+		return "", fmt.Errorf("Failed to move backup file into place: %w", err)
+	}
+
+	return backupPath, nil
+}
+
+// upgradeSnapshot writes a backup archive of the database, as it is before schema updates are applied to it, to the
+// upgrade snapshots directory, and then removes all but the most recent snapshots. The metadata records the given
+// schema versions of the database, and the version of the project this cluster member last ran.
+//
+// So that the previous version of the project can restore the snapshot with RestoreFromBackup, the cluster members in
+// the dump are recorded at the given schema versions and without API extensions, as the members that were upgraded
+// first have already recorded their new ones. The database itself is left unchanged.
+func (db *DqliteDB) upgradeSnapshot(ctx context.Context, tx *sql.Tx, schemaInternal uint64, schemaExternal uint64) error {
+	backup := &Backup{
+		Metadata: types.DatabaseBackup{
+			CreatedAt:             time.Now().UTC(),
+			Name:                  db.memberName(),
+			SchemaInternalVersion: schemaInternal,
+			SchemaExternalVersion: schemaExternal,
+			APIExtensions:         extensions.Extensions{},
+		},
+	}
+
+	var err error
+	backup.Metadata.Version, err = update.GetClusterMemberVersion(ctx, tx, db.memberName())
+	if err != nil {
+		return fmt.Errorf("Failed to get cluster member version: %w", err)
+	}
+
+	backup.Metadata.Members, err = update.GetBackupClusterMembers(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("Failed to get cluster members: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "SAVEPOINT upgrade_snapshot")
+	if err != nil {
+		return err
+	}
+
+	err = update.ResetClusterMemberVersions(ctx, tx, schemaInternal, schemaExternal)
+	if err != nil {
+		return fmt.Errorf("Failed to reset cluster member versions: %w", err)
+	}
+
+	backup.Dump, err = query.Dump(ctx, tx, false)
+	if err != nil {
+		return fmt.Errorf("Failed to dump database: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, "ROLLBACK TO upgrade_snapshot; RELEASE upgrade_snapshot")
+	if err != nil {
+		return err
+	}
+
+	snapshotPath, err := backup.writeFile(db.os.SnapshotsDir)
+	if err != nil {
+		return err
+	}
+
+	logger.Warn("Created database snapshot before applying schema updates", logger.Ctx{"archive": snapshotPath, "schemaInternal": schemaInternal, "schemaExternal": schemaExternal})
+
+	snapshots, err := ListBackups(db.os.SnapshotsDir)
+	if err != nil {
+		return err
+	}
+
+	for _, snapshot := range expiredBackups(snapshots, maxUpgradeSnapshots, 0, time.Now()) {
+		logger.Info("Removing old database snapshot", logger.Ctx{"name": snapshot.Name})
+		err := os.Remove(filepath.Join(db.os.SnapshotsDir, snapshot.Name))
+		if err != nil {
+			return fmt.Errorf("Failed to remove old snapshot %q: %w", snapshot.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// ListBackups returns the backup archives stored in the given directory, from oldest to newest.
+// The creation time of each backup is the one recorded in its name, as the modification time of the file changes when
+// it is copied.
+func ListBackups(dir string) ([]types.DatabaseBackupFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("Failed to read backups directory: %w", err)
+	}
+
+	backups := make([]types.DatabaseBackupFile, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+
+		createdAt, ok := backupFileTime(entry.Name())
+		if !ok {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to get information about backup %q: %w", entry.Name(), err)
+		}
+
+		backups = append(backups, types.DatabaseBackupFile{
+			Name:      entry.Name(),
+			Size:      info.Size(),
+			CreatedAt: createdAt,
+		})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].CreatedAt.Equal(backups[j].CreatedAt) {
+			return backups[i].Name < backups[j].Name
+		}
+
+		return backups[i].CreatedAt.Before(backups[j].CreatedAt)
+	})
+
+	return backups, nil
+}
+
+// expiredBackups returns the backups, ordered from oldest to newest, that fall outside of the retention rules.
+// Only the most recent keepLast backups are kept, and backups older than maxAge are removed. Either rule is disabled if
+// not positive. The most recent backup is always kept, so that a backup remains even if newer ones keep failing.
+func expiredBackups(backups []types.DatabaseBackupFile, keepLast int, maxAge time.Duration, now time.Time) []types.DatabaseBackupFile {
+	expired := []types.DatabaseBackupFile{}
+	for i, backup := range backups {
+		newer := len(backups) - 1 - i
+		if newer == 0 {
+			break
+		}
+
+		if (keepLast > 0 && newer >= keepLast) || (maxAge > 0 && now.Sub(backup.CreatedAt) > maxAge) {
+			expired = append(expired, backup)
+		}
+	}
+
+	return expired
+}
@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"math/rand"
@@ -54,6 +55,17 @@ func (db *DqliteDB) Open(ext extensions.Extensions, bootstrap bool, project stri
 		if err != nil {
 			return fmt.Errorf("Open dqlite: %w", err)
 		}
+
+		// Keep enough idle connections for concurrent read-only transactions, on top of the default of 2.
+		db.db.SetMaxIdleConns(cap(db.readSlots) + 2)
+	}
+
+	// Load the backup we are restoring from, if any, before applying any schema updates to it.
+	if bootstrap && db.restoreDump != "" {
+		err = db.restore(ctx, ext)
+		if err != nil {
+			return err
+		}
 	}
 
 	err = db.waitUpgrade(bootstrap, ext)
@@ -76,6 +88,17 @@ func (db *DqliteDB) Open(ext extensions.Extensions, bootstrap bool, project stri
 		return err
 	}
 
+	// Having caught up with the rest of the cluster, clear any upgrade status recorded by a previous version.
+	// When bootstrapping, there is no cluster member record yet.
+	if !bootstrap {
+		err = query.Transaction(ctx, db.db, func(ctx context.Context, tx *sql.Tx) error {
+			return update.UpdateClusterMemberUpgradeStatus(ctx, tx, "", db.memberName())
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to clear upgrade status: %w", err)
+		}
+	}
+
 	db.statusLock.Lock()
 	db.status = types.DatabaseReady
 	db.statusLock.Unlock()
@@ -189,6 +212,7 @@ func (db *DqliteDB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error
 		}
 
 		newSchema.Check(checkVersions)
+		newSchema.Snapshot(db.upgradeSnapshot)
 	}
 
 	err := db.retry(context.TODO(), func(_ context.Context) error {
@@ -249,23 +273,148 @@ func (db *DqliteDB) waitUpgrade(bootstrap bool, ext extensions.Extensions) error
 
 // Transaction handles performing a transaction on the dqlite database.
 func (db *DqliteDB) Transaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) error {
+	start := time.Now()
+	err := db.transaction(outerCtx, f)
+	db.recordTransaction(start, err)
+
+	return err
+}
+
+func (db *DqliteDB) transaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) error {
 	status := db.Status()
 	if status != types.DatabaseWaiting && status != types.DatabaseReady {
 		return api.StatusErrorf(http.StatusServiceUnavailable, "Database is not ready yet: %v", status)
 	}
 
-	return db.retry(outerCtx, func(ctx context.Context) error {
-		err := query.Transaction(ctx, db.db, f)
+	// Once the schema is up to date, check whether the transaction recorded changes to watched tables, so that only the
+	// cluster member that made the changes notifies the others.
+	var changed bool
+	txFunc := f
+	if status == types.DatabaseReady && db.schema != nil && len(db.schema.WatchedTables()) > 0 {
+		txFunc = func(ctx context.Context, tx *sql.Tx) error {
+			before, err := getChangeSequence(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			err = f(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			after, err := getChangeSequence(ctx, tx)
+			if err != nil {
+				return err
+			}
+
+			changed = after > before
+
+			return nil
+		}
+	}
+
+	err := db.retry(outerCtx, func(ctx context.Context) error {
+		err := query.Transaction(ctx, db.db, txFunc)
 		if errors.Is(err, context.DeadlineExceeded) {
 			// If the query timed out it likely means that the leader has abruptly become unreachable.
 			// Now that this query has been cancelled, a leader election should have taken place by now.
 			// So let's retry the transaction once more in case the global database is now available again.
 			logger.Warn("Transaction timed out. Retrying once", logger.Ctx{"err": err})
-			return query.Transaction(ctx, db.db, f)
+			return query.Transaction(ctx, db.db, txFunc)
 		}
 
 		return err
 	})
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		db.changesCommitted()
+	}
+
+	return nil
+}
+
+// ReadTransaction handles performing a read-only transaction on the dqlite database.
+// SQLite's query_only pragma makes any attempt to modify the database within the transaction fail. Up to the configured
+// number of read-only transactions run concurrently, each on a connection of its own, so they do not queue behind writes.
+func (db *DqliteDB) ReadTransaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) error {
+	start := time.Now()
+	err := db.readTransaction(outerCtx, f)
+	db.recordTransaction(start, err)
+
+	return err
+}
+
+func (db *DqliteDB) readTransaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) error {
+	status := db.Status()
+	if status != types.DatabaseWaiting && status != types.DatabaseReady {
+		return api.StatusErrorf(http.StatusServiceUnavailable, "Database is not ready yet: %v", status)
+	}
+
+	select {
+	case db.readSlots <- struct{}{}:
+	case <-outerCtx.Done():
+		return outerCtx.Err()
+	}
+
+	defer func() { <-db.readSlots }()
+
+	return db.retry(outerCtx, func(ctx context.Context) error {
+		// The pragma applies to the whole connection, so use a dedicated one that is not handed to other transactions
+		// until the pragma is disabled again.
+		conn, err := db.db.Conn(ctx)
+		if err != nil {
+			return fmt.Errorf("Failed to get database connection: %w", err)
+		}
+
+		defer func() { _ = conn.Close() }()
+
+		_, err = conn.ExecContext(ctx, "PRAGMA query_only = ON")
+		if err != nil {
+			return fmt.Errorf("Failed to start read-only transaction: %w", err)
+		}
+
+		defer func() {
+			// Always reset the pragma, even if the transaction was cancelled or panicked.
+			_, err := conn.ExecContext(context.Background(), "PRAGMA query_only = OFF")
+			if err == nil {
+				return
+			}
+
+			logger.Error("Failed to end read-only transaction, discarding connection", logger.Ctx{"error": err})
+
+			// Returning driver.ErrBadConn removes the connection from the pool instead of returning it read-only.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}()
+
+		tx, err := conn.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("Failed to begin transaction: %w", err)
+		}
+
+		// Release the connection from the transaction before the pragma is reset. This is a no-op once committed.
+		defer func() { _ = tx.Rollback() }()
+
+		err = f(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Commit()
+		if errors.Is(err, sql.ErrTxDone) {
+			return nil
+		}
+
+		return err
+	})
+}
+
+// SetReadConns sets the number of read-only transactions that can run concurrently. It must be called before dqlite is
+// started.
+func (db *DqliteDB) SetReadConns(readConns int) {
+	db.readSlots = make(chan struct{}, readConns)
 }
 
 func (db *DqliteDB) retry(ctx context.Context, f func(context.Context) error) error {
@@ -1,10 +1,17 @@
 package db
 
 import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,11 +19,13 @@ import (
 	"github.com/canonical/lxd/lxd/db/schema"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v3"
 
 	"github.com/canonical/microcluster/v3/cluster"
 	"github.com/canonical/microcluster/v3/internal/db/update"
 	"github.com/canonical/microcluster/v3/internal/extensions"
 	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/rest/types"
 )
 
 type dbSuite struct {
@@ -161,6 +170,9 @@ func (s *dbSuite) Test_waitUpgradeSchema() {
 		db, err := NewTestDB([]schema.Update{})
 		s.NoError(err)
 
+		// A snapshot of the database is taken before schema updates are applied.
+		db.os.SnapshotsDir = s.T().TempDir()
+
 		ctx := context.Background()
 		tx, err := db.db.BeginTx(ctx, nil)
 		s.NoError(err)
@@ -340,6 +352,9 @@ func (s *dbSuite) Test_waitUpgradeAPI() {
 		db, err := NewTestDB([]schema.Update{})
 		s.NoError(err)
 
+		// A snapshot of the database is taken before schema updates are applied.
+		db.os.SnapshotsDir = s.T().TempDir()
+
 		ctx := context.Background()
 		tx, err := db.db.BeginTx(ctx, nil)
 		s.NoError(err)
@@ -507,6 +522,9 @@ func (s *dbSuite) Test_waitUpgradeSchemaAndAPI() {
 		db, err := NewTestDB([]schema.Update{})
 		s.NoError(err)
 
+		// A snapshot of the database is taken before schema updates are applied.
+		db.os.SnapshotsDir = s.T().TempDir()
+
 		ctx := context.Background()
 		tx, err := db.db.BeginTx(ctx, nil)
 		s.NoError(err)
@@ -656,6 +674,8 @@ func NewTestDB(extensionsExternal []schema.Update) (*DqliteDB, error) {
 		memberName: func() string { return fmt.Sprintf("cluster-member-%d", 0) },
 		listenAddr: *api.NewURL().Host("10.0.0.0:8443"),
 		upgradeCh:  make(chan struct{}, 1),
+		watchCh:    make(chan struct{}, 1),
+		readSlots:  make(chan struct{}, DefaultReadConns),
 		os:         &sys.OS{},
 	}
 	db.db, err = sql.Open("sqlite3", ":memory:")
@@ -676,3 +696,939 @@ func NewTestDB(extensionsExternal []schema.Update) (*DqliteDB, error) {
 
 	return db, nil
 }
+
+// Ensures RequireUpgrade only runs the upgrade hook once and records the upgrade progress of the cluster member.
+func (s *dbSuite) Test_requireUpgrade() {
+	db, err := NewTestDB([]schema.Update{})
+	s.NoError(err)
+
+	db.status = types.DatabaseReady
+
+	ctx := context.Background()
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.CreateCoreClusterMember(ctx, tx, cluster.CoreClusterMember{
+			Name:          db.memberName(),
+			Address:       db.listenAddr.URL.Host,
+			Certificate:   "test-cert-0",
+			APIExtensions: extensions.Extensions{},
+			Role:          "voter",
+		})
+
+		return err
+	})
+	s.NoError(err)
+
+	hookRuns := make(chan struct{}, 2)
+	db.SetUpgradeRequiredHook(func(ctx context.Context) error {
+		hookRuns <- struct{}{}
+		return nil
+	})
+
+	s.Equal(types.MemberStatus(""), db.UpgradeStatus())
+
+	db.RequireUpgrade("test")
+	db.RequireUpgrade("test")
+	s.Equal(types.MemberNeedsUpgrade, db.UpgradeStatus())
+
+	s.Eventually(func() bool { return db.UpgradeStatus() == types.MemberUpgrading }, 5*time.Second, 10*time.Millisecond)
+	s.Len(hookRuns, 1)
+
+	var status []string
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		status, err = query.SelectStrings(ctx, tx, "SELECT upgrade_status FROM core_cluster_members")
+
+		return err
+	})
+	s.NoError(err)
+	s.Equal([]string{string(types.MemberUpgrading)}, status)
+}
+
+// Ensures RequireUpgrade keeps the cluster member in need of an upgrade, and tries again later, if none was started.
+func (s *dbSuite) Test_requireUpgradeNotStarted() {
+	db, err := NewTestDB([]schema.Update{})
+	s.NoError(err)
+
+	db.status = types.DatabaseReady
+
+	ctx := context.Background()
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.CreateCoreClusterMember(ctx, tx, cluster.CoreClusterMember{
+			Name:          db.memberName(),
+			Address:       db.listenAddr.URL.Host,
+			Certificate:   "test-cert-0",
+			APIExtensions: extensions.Extensions{},
+			Role:          "voter",
+		})
+
+		return err
+	})
+	s.NoError(err)
+
+	s.T().Setenv(sys.SchemaUpdate, "")
+
+	hookRuns := make(chan struct{}, 2)
+	db.SetUpgradeRequiredHook(func(ctx context.Context) error {
+		hookRuns <- struct{}{}
+		return fmt.Errorf("No upgrade available")
+	})
+
+	upgradeRunning := func() bool {
+		db.upgradeLock.Lock()
+		defer db.upgradeLock.Unlock()
+
+		return db.upgradeRunning
+	}
+
+	for i := 0; i < 2; i++ {
+		db.RequireUpgrade("test")
+		s.Eventually(func() bool { return len(hookRuns) == i+1 && !upgradeRunning() }, 5*time.Second, 10*time.Millisecond)
+		s.Equal(types.MemberNeedsUpgrade, db.UpgradeStatus())
+	}
+
+	var status []string
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		status, err = query.SelectStrings(ctx, tx, "SELECT upgrade_status FROM core_cluster_members")
+
+		return err
+	})
+	s.NoError(err)
+	s.Equal([]string{string(types.MemberNeedsUpgrade)}, status)
+}
+
+// Ensures a backup contains a consistent dump of the database along with its metadata.
+func (s *dbSuite) Test_backup() {
+	db, err := NewTestDB([]schema.Update{})
+	s.NoError(err)
+
+	db.status = types.DatabaseReady
+
+	ctx := context.Background()
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := cluster.CreateCoreClusterMember(ctx, tx, cluster.CoreClusterMember{
+			Name:          db.memberName(),
+			Address:       db.listenAddr.URL.Host,
+			Certificate:   "test-cert-0",
+			APIExtensions: extensions.Extensions{},
+			Role:          "voter",
+		})
+		if err != nil {
+			return err
+		}
+
+		// Make the dump larger than a single chunk of the archive.
+		_, err = tx.ExecContext(ctx, "CREATE TABLE test (data TEXT); INSERT INTO test (data) VALUES (?)", strings.Repeat("x", 2*backupChunkSize+1))
+
+		return err
+	})
+	s.NoError(err)
+
+	backup, err := db.Backup(ctx, "1.0")
+	s.NoError(err)
+	s.Equal(db.memberName(), backup.Metadata.Name)
+	s.Equal("1.0", backup.Metadata.Version)
+	s.Len(backup.Metadata.Members, 1)
+	s.Equal(db.listenAddr.URL.Host, backup.Metadata.Members[0].Address.String())
+	s.Contains(backup.Dump, "INSERT INTO core_cluster_members")
+
+	buf := bytes.Buffer{}
+	err = backup.WriteArchive(&buf)
+	s.NoError(err)
+
+	gzReader, err := gzip.NewReader(&buf)
+	s.NoError(err)
+
+	files := map[string][]byte{}
+	tarReader := tar.NewReader(gzReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+
+		s.NoError(err)
+
+		files[header.Name], err = io.ReadAll(tarReader)
+		s.NoError(err)
+	}
+
+	s.Equal(backup.Dump, string(files[BackupDumpFile]))
+
+	var metadata types.DatabaseBackup
+	err = yaml.Unmarshal(files[BackupMetadataFile], &metadata)
+	s.NoError(err)
+	s.Equal(backup.Metadata.Members, metadata.Members)
+	s.True(backup.Metadata.CreatedAt.Equal(metadata.CreatedAt))
+}
+
+// Ensures a backup can be read back from an archive or a plain SQL dump, and restored to a new cluster member.
+func (s *dbSuite) Test_restore() {
+	tests := []struct {
+		name         string
+		memberName   string
+		schemaAhead  bool
+		tables       string
+		extensions   extensions.Extensions
+		expectErr    bool
+		expectMember string
+	}{
+		{
+			name:         "Restore with a cluster member from the backup",
+			memberName:   "cluster-member-1",
+			extensions:   extensions.Extensions{"ext_a"},
+			expectMember: "cluster-member-1",
+		},
+		{
+			name:       "Restore with a new cluster member",
+			memberName: "cluster-member-new",
+			extensions: extensions.Extensions{"ext_a", "ext_b"},
+		},
+		{
+			name:        "Restore a backup with a newer schema",
+			memberName:  "cluster-member-1",
+			schemaAhead: true,
+			extensions:  extensions.Extensions{"ext_a"},
+			expectErr:   true,
+		},
+		{
+			name:       "Restore a backup with unsupported API extensions",
+			memberName: "cluster-member-1",
+			extensions: extensions.Extensions{"ext_b"},
+			expectErr:  true,
+		},
+		{
+			name:         "Restore a backup with a table recreated after the tables referencing it",
+			memberName:   "cluster-member-1",
+			tables:       "INSERT INTO parent (id) VALUES (1); INSERT INTO child (id, parent_id) VALUES (1, 1)",
+			extensions:   extensions.Extensions{"ext_a"},
+			expectMember: "cluster-member-1",
+		},
+		{
+			name:       "Restore a backup with a row referencing a missing row",
+			memberName: "cluster-member-1",
+			tables:     "INSERT INTO child (id, parent_id) VALUES (1, 2)",
+			extensions: extensions.Extensions{"ext_a"},
+			expectErr:  true,
+		},
+	}
+
+	for i, c := range tests {
+		s.T().Logf("%s (case %d)", c.name, i)
+
+		source, err := NewTestDB([]schema.Update{})
+		s.NoError(err)
+
+		source.status = types.DatabaseReady
+
+		// Allow recreating tables and leaving rows that reference missing ones.
+		source.db.SetMaxOpenConns(1)
+		_, err = source.db.Exec("PRAGMA foreign_keys=OFF")
+		s.NoError(err)
+
+		ctx := context.Background()
+		err = source.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			for j := 0; j < 3; j++ {
+				_, err := cluster.CreateCoreClusterMember(ctx, tx, cluster.CoreClusterMember{
+					Name:          fmt.Sprintf("cluster-member-%d", j),
+					Address:       fmt.Sprintf("10.0.0.%d:8443", j),
+					Certificate:   fmt.Sprintf("test-cert-%d", j),
+					APIExtensions: extensions.Extensions{"ext_a"},
+					Role:          "voter",
+				})
+				if err != nil {
+					return err
+				}
+			}
+
+			if c.schemaAhead {
+				_, err := tx.ExecContext(ctx, "UPDATE core_cluster_members SET schema_internal = 1000")
+				if err != nil {
+					return err
+				}
+			}
+
+			// Recreate the parent table as a schema update would, so that it is dumped after the child table.
+			if c.tables != "" {
+				stmt := `
+CREATE TABLE parent (id INTEGER PRIMARY KEY);
+CREATE TABLE child (id INTEGER PRIMARY KEY, parent_id INTEGER NOT NULL REFERENCES parent (id));
+CREATE TABLE parent_new (id INTEGER PRIMARY KEY, name TEXT NOT NULL DEFAULT '');
+DROP TABLE parent;
+ALTER TABLE parent_new RENAME TO parent;
+`
+				_, err := tx.ExecContext(ctx, stmt+c.tables)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err := cluster.CreateCoreTokenRecord(ctx, tx, cluster.CoreTokenRecord{Name: "token", Secret: "secret"})
+
+			return err
+		})
+		s.NoError(err)
+
+		backup, err := source.Backup(ctx, "1.0")
+		s.NoError(err)
+
+		// Read the backup back both from an archive and from a plain SQL dump.
+		buf := bytes.Buffer{}
+		err = backup.WriteArchive(&buf)
+		s.NoError(err)
+
+		archiveBackup, err := ReadBackup(&buf)
+		s.NoError(err)
+		s.Equal(backup.Dump, archiveBackup.Dump)
+		s.Equal(backup.Metadata.Members, archiveBackup.Metadata.Members)
+
+		plainBackup, err := ReadBackup(strings.NewReader(backup.Dump))
+		s.NoError(err)
+		s.Equal(backup.Dump, plainBackup.Dump)
+
+		target := &DqliteDB{
+			ctx:        context.Background(),
+			memberName: func() string { return c.memberName },
+			listenAddr: *api.NewURL().Host("10.0.1.0:8443"),
+			os:         &sys.OS{},
+			status:     types.DatabaseReady,
+		}
+
+		target.db, err = sql.Open("sqlite3", ":memory:")
+		s.NoError(err)
+
+		// Enforce foreign keys like dqlite does.
+		target.db.SetMaxOpenConns(1)
+		_, err = target.db.Exec("PRAGMA foreign_keys=ON")
+		s.NoError(err)
+
+		target.SetSchema([]schema.Update{}, nil)
+		target.SetRestore(archiveBackup.Dump)
+
+		err = target.restore(ctx, c.extensions)
+		if c.expectErr {
+			s.Error(err)
+			continue
+		}
+
+		s.NoError(err)
+		s.Empty(target.restoreDump)
+
+		// Foreign keys are enforced again once the backup is loaded.
+		var foreignKeys int
+		err = target.db.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys)
+		s.NoError(err)
+		s.Equal(1, foreignKeys)
+
+		_, err = target.schema.Ensure(target.db)
+		s.NoError(err)
+
+		var members []string
+		var tokens []string
+		err = target.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			members, err = query.SelectStrings(ctx, tx, "SELECT name FROM core_cluster_members")
+			if err != nil {
+				return err
+			}
+
+			tokens, err = query.SelectStrings(ctx, tx, "SELECT name FROM core_token_records")
+
+			return err
+		})
+		s.NoError(err)
+		s.Empty(tokens)
+
+		if c.expectMember == "" {
+			s.Empty(members)
+		} else {
+			s.Equal([]string{c.expectMember}, members)
+		}
+	}
+}
+
+// Ensures a copy of the dqlite directory is not mistaken for a backup archive.
+func (s *dbSuite) Test_readBackupDatabaseDir() {
+	buf := bytes.Buffer{}
+	gzWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzWriter)
+
+	err := tarWriter.WriteHeader(&tar.Header{Name: "database/", Typeflag: tar.TypeDir, Mode: 0700})
+	s.NoError(err)
+
+	s.NoError(tarWriter.Close())
+	s.NoError(gzWriter.Close())
+
+	_, err = ReadBackup(&buf)
+	s.Error(err)
+
+	_, err = ReadBackup(strings.NewReader(""))
+	s.Error(err)
+}
+
+// Ensures scheduled backups are removed according to the retention rules.
+func (s *dbSuite) Test_expiredBackups() {
+	now := time.Now()
+	backups := []types.DatabaseBackupFile{}
+	for i := 5; i > 0; i-- {
+		createdAt := now.Add(-time.Duration(i) * time.Hour)
+		backups = append(backups, types.DatabaseBackupFile{Name: BackupFileName(createdAt), CreatedAt: createdAt})
+	}
+
+	tests := []struct {
+		name          string
+		backups       []types.DatabaseBackupFile
+		keepLast      int
+		maxAge        time.Duration
+		expectExpired int
+	}{
+		{
+			name:          "No retention rules",
+			backups:       backups,
+			expectExpired: 0,
+		},
+		{
+			name:          "Keep last 2",
+			backups:       backups,
+			keepLast:      2,
+			expectExpired: 3,
+		},
+		{
+			name:          "Maximum age",
+			backups:       backups,
+			maxAge:        150 * time.Minute,
+			expectExpired: 3,
+		},
+		{
+			name:          "Both rules",
+			backups:       backups,
+			keepLast:      4,
+			maxAge:        270 * time.Minute,
+			expectExpired: 1,
+		},
+		{
+			name:          "Most recent backup is always kept",
+			backups:       backups,
+			keepLast:      -1,
+			maxAge:        time.Minute,
+			expectExpired: 4,
+		},
+		{
+			name:          "No backups",
+			backups:       []types.DatabaseBackupFile{},
+			keepLast:      1,
+			expectExpired: 0,
+		},
+	}
+
+	for i, t := range tests {
+		s.T().Logf("%s (case %d)", t.name, i)
+
+		expired := expiredBackups(t.backups, t.keepLast, t.maxAge, now)
+		s.Len(expired, t.expectExpired)
+		s.Equal(t.backups[:t.expectExpired], expired)
+	}
+}
+
+// Ensures scheduled backups are written to the backups directory and listed from oldest to newest.
+func (s *dbSuite) Test_scheduledBackup() {
+	db, err := NewTestDB([]schema.Update{})
+	s.NoError(err)
+
+	db.status = types.DatabaseReady
+	db.os.BackupsDir = s.T().TempDir()
+
+	// Leave an older backup, copied in after the newer one so that its modification time is more recent, and some
+	// unrelated files in the directory.
+	olderCreatedAt := time.Now().Add(-2 * time.Hour).UTC().Truncate(time.Second)
+	older := filepath.Join(db.os.BackupsDir, BackupFileName(olderCreatedAt))
+	s.NoError(os.WriteFile(filepath.Join(db.os.BackupsDir, "unrelated"), []byte{}, 0600))
+	s.NoError(os.WriteFile(filepath.Join(db.os.BackupsDir, "db_backup.unrelated.tar.gz"), []byte{}, 0600))
+
+	ctx := context.Background()
+	err = db.ScheduledBackup(ctx, "1.0", 0, 5, 0)
+	s.NoError(err)
+
+	s.NoError(os.WriteFile(older, []byte{}, 0600))
+	s.NoError(os.Chtimes(older, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+
+	backups, err := ListBackups(db.os.BackupsDir)
+	s.NoError(err)
+	s.Len(backups, 2)
+	s.Equal(filepath.Base(older), backups[0].Name)
+	s.Equal(olderCreatedAt, backups[0].CreatedAt)
+	s.NotZero(backups[1].Size)
+
+	lastAttempt, lastError := db.BackupStatus()
+	s.NotZero(lastAttempt)
+	s.Empty(lastError)
+
+	// A backup is not due yet, so nothing changes.
+	err = db.ScheduledBackup(ctx, "1.0", time.Hour, 5, 0)
+	s.NoError(err)
+
+	backups, err = ListBackups(db.os.BackupsDir)
+	s.NoError(err)
+	s.Len(backups, 2)
+
+	// Backups can be pruned without taking a new one.
+	err = db.PruneBackups(5, time.Hour)
+	s.NoError(err)
+
+	backups, err = ListBackups(db.os.BackupsDir)
+	s.NoError(err)
+	s.Len(backups, 1)
+
+	// Once a backup is due, the retention rules are applied.
+	err = db.ScheduledBackup(ctx, "1.0", 0, 1, 0)
+	s.NoError(err)
+
+	backups, err = ListBackups(db.os.BackupsDir)
+	s.NoError(err)
+	s.Len(backups, 1)
+}
+
+func (s *dbSuite) Test_watch() {
+	db, err := NewTestDB([]schema.Update{})
+	s.Require().NoError(err)
+
+	// Watchers and the dispatcher share the in-memory database, which only exists for a single connection.
+	db.db.SetMaxOpenConns(1)
+	db.status = types.DatabaseReady
+
+	hookCh := make(chan struct{}, 1)
+	db.SetChangeHook(func(ctx context.Context) error {
+		select {
+		case hookCh <- struct{}{}:
+		default:
+		}
+
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "CREATE TABLE test (id INTEGER PRIMARY KEY, value TEXT); CREATE TABLE other (id INTEGER PRIMARY KEY); CREATE TABLE unwatched (id INTEGER PRIMARY KEY); INSERT INTO test (value) VALUES ('before')")
+		return err
+	})
+	s.Require().NoError(err)
+
+	// Triggers are only created for registered tables when schema updates are applied.
+	db.Schema().WatchTables([]string{"missing"})
+	_, err = db.Schema().Ensure(db.db)
+	s.Error(err)
+
+	db.Schema().WatchTables([]string{"test", "other"})
+	_, err = db.Schema().Ensure(db.db)
+	s.Require().NoError(err)
+
+	_, err = db.Watch(ctx, "missing")
+	s.Error(err)
+
+	_, err = db.Watch(ctx, "core_change_log")
+	s.Error(err)
+
+	_, err = db.Watch(ctx, "unwatched")
+	s.Error(err)
+
+	changes, err := db.Watch(ctx, "TEST")
+	s.Require().NoError(err)
+
+	// Watching a table again does not record its changes twice.
+	otherCtx, otherCancel := context.WithCancel(ctx)
+	otherChanges, err := db.Watch(otherCtx, "test", "other")
+	s.Require().NoError(err)
+
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO test (value) VALUES ('a'); UPDATE test SET value = 'b' WHERE id = 2; INSERT INTO other (id) VALUES (7); DELETE FROM test WHERE id = 1")
+		return err
+	})
+	s.Require().NoError(err)
+
+	select {
+	case <-hookCh:
+	case <-time.After(5 * time.Second):
+		s.Fail("Change hook was not called")
+	}
+
+	receive := func(ch <-chan types.DatabaseChange, count int) []types.DatabaseChange {
+		received := []types.DatabaseChange{}
+		for len(received) < count {
+			select {
+			case change := <-ch:
+				received = append(received, change)
+			case <-time.After(5 * time.Second):
+				s.FailNow("Timed out waiting for database changes", "received %d of %d", len(received), count)
+			}
+		}
+
+		return received
+	}
+
+	received := receive(changes, 3)
+	s.Equal("test", received[0].Table)
+	s.Equal(types.DatabaseChangeInsert, received[0].Operation)
+	s.Equal(int64(2), received[0].RowID)
+	s.Equal(types.DatabaseChangeUpdate, received[1].Operation)
+	s.Equal(int64(2), received[1].RowID)
+	s.Equal(types.DatabaseChangeDelete, received[2].Operation)
+	s.Equal(int64(1), received[2].RowID)
+	s.NotZero(received[2].CreatedAt)
+	s.Less(received[0].ID, received[1].ID)
+
+	received = receive(otherChanges, 4)
+	s.Equal("other", received[2].Table)
+	s.Equal(int64(7), received[2].RowID)
+
+	// Cancelling a watcher closes its channel.
+	otherCancel()
+	for range otherChanges {
+	}
+
+	// Pruning only removes old changes.
+	err = db.PruneChangeLog(ctx, time.Hour)
+	s.NoError(err)
+
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		count, err := query.Count(ctx, tx, "core_change_log", "")
+		s.Equal(4, count)
+
+		return err
+	})
+	s.NoError(err)
+
+	// Another cluster member sharing the database does not notify the others of changes it did not make.
+	peer := &DqliteDB{ctx: ctx, db: db.db, schema: db.schema, status: types.DatabaseReady, watchCh: make(chan struct{}, 1), readSlots: make(chan struct{}, 1)}
+	peer.SetChangeHook(func(ctx context.Context) error {
+		s.Fail("Change hook of another cluster member was called")
+		return nil
+	})
+
+	err = peer.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := query.Count(ctx, tx, "core_change_log", "")
+		return err
+	})
+	s.NoError(err)
+
+	// Delivering changes, reading or pruning the change log do not notify the other cluster members again.
+	select {
+	case <-hookCh:
+		s.Fail("Change hook was called for a transaction that did not record changes")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A watcher that does not keep up is dropped, closing its channel.
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for i := 0; i < 2*watcherBufferSize; i++ {
+			_, err := tx.ExecContext(ctx, "INSERT INTO test (value) VALUES ('lagging')")
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	s.Require().NoError(err)
+
+	s.Eventually(func() bool {
+		db.watchLock.Lock()
+		defer db.watchLock.Unlock()
+
+		return len(db.watchers) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+
+	received = []types.DatabaseChange{}
+	for change := range changes {
+		received = append(received, change)
+	}
+
+	s.Len(received, watcherBufferSize)
+}
+
+func (s *dbSuite) Test_readTransaction() {
+	db, err := NewTestDB([]schema.Update{})
+	s.Require().NoError(err)
+
+	db.db.SetMaxOpenConns(1)
+	db.status = types.DatabaseReady
+	// The number of read-only transactions does not change the connections dqlite makes while looking for the leader.
+	maxConns := db.maxConns
+	db.SetReadConns(4)
+	s.Equal(4, cap(db.readSlots))
+	s.Equal(maxConns, db.maxConns)
+
+	db.SetReadConns(1)
+
+	ctx := context.Background()
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "CREATE TABLE test (id INTEGER PRIMARY KEY); INSERT INTO test (id) VALUES (1)")
+		return err
+	})
+	s.Require().NoError(err)
+
+	// Reads succeed.
+	err = db.ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		count, err := query.Count(ctx, tx, "test", "")
+		s.Equal(1, count)
+
+		return err
+	})
+	s.NoError(err)
+
+	// Writes fail.
+	err = db.ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO test (id) VALUES (2)")
+		return err
+	})
+	s.Error(err)
+
+	// The connection can still be used for writes afterwards.
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO test (id) VALUES (3)")
+		return err
+	})
+	s.NoError(err)
+
+	// The connection is reset even if the read-only transaction is cancelled or panics.
+	cancelCtx, cancel := context.WithCancel(ctx)
+	err = db.ReadTransaction(cancelCtx, func(ctx context.Context, tx *sql.Tx) error {
+		cancel()
+
+		return ctx.Err()
+	})
+	s.ErrorIs(err, context.Canceled)
+
+	s.Panics(func() {
+		_ = db.ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error { panic("read failed") })
+	})
+
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "INSERT INTO test (id) VALUES (4)")
+		return err
+	})
+	s.NoError(err)
+
+	// Read-only transactions beyond the configured number wait for one to finish.
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		_ = db.ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			close(started)
+			<-done
+
+			return nil
+		})
+	}()
+
+	<-started
+	timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+
+	err = db.ReadTransaction(timeoutCtx, func(ctx context.Context, tx *sql.Tx) error { return nil })
+	s.ErrorIs(err, context.DeadlineExceeded)
+	close(done)
+
+	// Read-only transactions are unavailable until the database is open.
+	db.status = types.DatabaseNotReady
+	err = db.ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error { return nil })
+	s.Error(err)
+}
+
+// Ensures transactions over the slow transaction threshold are recorded along with their caller.
+func (s *dbSuite) Test_slowTransactions() {
+	db, err := NewTestDB([]schema.Update{})
+	s.Require().NoError(err)
+
+	db.status = types.DatabaseReady
+
+	tests := []struct {
+		name       string
+		threshold  time.Duration
+		txErr      error
+		expectSlow bool
+	}{
+		{
+			name:      "Disabled threshold",
+			threshold: 0,
+		},
+		{
+			name:      "Transaction under the threshold",
+			threshold: time.Hour,
+		},
+		{
+			name:       "Transaction over the threshold",
+			threshold:  time.Nanosecond,
+			expectSlow: true,
+		},
+		{
+			name:       "Failed transaction over the threshold",
+			threshold:  time.Nanosecond,
+			txErr:      fmt.Errorf("Failed"),
+			expectSlow: true,
+		},
+	}
+
+	ctx := context.Background()
+	expected := 0
+	for i, c := range tests {
+		s.T().Logf("%s (case %d)", c.name, i)
+
+		db.SetSlowTransactionThreshold(c.threshold)
+		err := db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+			time.Sleep(time.Millisecond)
+			return c.txErr
+		})
+		s.ErrorIs(err, c.txErr)
+
+		if c.expectSlow {
+			expected++
+		}
+
+		slowTransactions := db.SlowTransactions()
+		s.Require().Len(slowTransactions, expected)
+		if !c.expectSlow {
+			continue
+		}
+
+		last := slowTransactions[len(slowTransactions)-1]
+		s.Contains(last.Caller, "Test_slowTransactions")
+		s.GreaterOrEqual(last.Duration, time.Millisecond)
+		if c.txErr != nil {
+			s.Equal(c.txErr.Error(), last.Error)
+		} else {
+			s.Empty(last.Error)
+		}
+	}
+
+	// Only the most recent slow transactions are kept.
+	for i := 0; i < maxSlowTransactions+5; i++ {
+		err := db.ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error { return nil })
+		s.NoError(err)
+	}
+
+	s.Len(db.SlowTransactions(), maxSlowTransactions)
+}
+
+// Ensures the page and row counts of the database are reported.
+func (s *dbSuite) Test_getTableInfo() {
+	db, err := NewTestDB([]schema.Update{})
+	s.Require().NoError(err)
+
+	db.status = types.DatabaseReady
+
+	ctx := context.Background()
+	info := &types.DatabaseInfo{}
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `CREATE TABLE "my""table" (id INTEGER PRIMARY KEY); INSERT INTO "my""table" (id) VALUES (1), (2), (3)`)
+		if err != nil {
+			return err
+		}
+
+		return getTableInfo(ctx, tx, info)
+	})
+	s.Require().NoError(err)
+
+	s.Positive(info.PageSize)
+	s.Positive(info.PageCount)
+	s.Equal(info.PageSize*info.PageCount, info.Size)
+
+	rows := map[string]int64{}
+	for _, table := range info.Tables {
+		rows[table.Name] = table.Rows
+	}
+
+	s.Equal(int64(3), rows[`my"table`])
+	s.Contains(rows, "core_cluster_members")
+	s.NotContains(rows, "sqlite_sequence")
+}
+
+// Ensures a snapshot taken before applying schema updates can be restored at the schema versions it was taken at,
+// without changing the database, and that only the most recent snapshots are kept.
+func (s *dbSuite) Test_upgradeSnapshot() {
+	db, err := NewTestDB([]schema.Update{})
+	s.Require().NoError(err)
+
+	db.status = types.DatabaseReady
+	db.os.SnapshotsDir = s.T().TempDir()
+
+	apiExtensions, err := extensions.NewExtensionRegistry(true)
+	s.Require().NoError(err)
+
+	schemaInternal, schemaExternal, _ := db.SchemaVersion()
+
+	ctx := context.Background()
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for i := 0; i < 2; i++ {
+			_, err := cluster.CreateCoreClusterMember(ctx, tx, cluster.CoreClusterMember{
+				Name:           fmt.Sprintf("cluster-member-%d", i),
+				Address:        fmt.Sprintf("10.0.0.%d:8443", i),
+				Certificate:    fmt.Sprintf("test-cert-%d", i),
+				SchemaInternal: schemaInternal,
+				SchemaExternal: schemaExternal + 1,
+				APIExtensions:  append(apiExtensions, "new_extension"),
+				Role:           "voter",
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return update.UpdateClusterMemberVersion(ctx, tx, "1.0", db.memberName())
+	})
+	s.Require().NoError(err)
+
+	// Leave older snapshots around to be removed.
+	for i := 0; i < maxUpgradeSnapshots+1; i++ {
+		createdAt := time.Now().Add(-time.Duration(i+1) * time.Hour)
+		path := filepath.Join(db.os.SnapshotsDir, BackupFileName(createdAt))
+		s.Require().NoError(os.WriteFile(path, []byte{}, 0600))
+		s.Require().NoError(os.Chtimes(path, createdAt, createdAt))
+	}
+
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return db.upgradeSnapshot(ctx, tx, schemaInternal, schemaExternal)
+	})
+	s.Require().NoError(err)
+
+	snapshots, err := ListBackups(db.os.SnapshotsDir)
+	s.Require().NoError(err)
+	s.Require().Len(snapshots, maxUpgradeSnapshots)
+
+	f, err := os.Open(filepath.Join(db.os.SnapshotsDir, snapshots[len(snapshots)-1].Name))
+	s.Require().NoError(err)
+
+	snapshot, err := ReadBackup(f)
+	s.NoError(f.Close())
+	s.Require().NoError(err)
+
+	s.Equal(db.memberName(), snapshot.Metadata.Name)
+	s.Equal("1.0", snapshot.Metadata.Version)
+	s.Equal(schemaInternal, snapshot.Metadata.SchemaInternalVersion)
+	s.Equal(schemaExternal, snapshot.Metadata.SchemaExternalVersion)
+	s.Len(snapshot.Metadata.Members, 2)
+
+	// The database itself is unchanged.
+	err = db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, versionsExternal, err := update.GetClusterMemberSchemaVersions(ctx, tx)
+		s.Equal([]uint64{schemaExternal + 1, schemaExternal + 1}, versionsExternal)
+
+		return err
+	})
+	s.NoError(err)
+
+	// The snapshot can be restored by a cluster member at the schema versions it was taken at.
+	restored, err := sql.Open("sqlite3", ":memory:")
+	s.Require().NoError(err)
+
+	defer func() { s.NoError(restored.Close()) }()
+
+	restored.SetMaxOpenConns(1)
+	err = query.Transaction(ctx, restored, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, dumpStatements(snapshot.Dump))
+		if err != nil {
+			return err
+		}
+
+		return update.RestoreClusterMembers(ctx, tx, schemaInternal, schemaExternal, apiExtensions, db.memberName())
+	})
+	s.NoError(err)
+}
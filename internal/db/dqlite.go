@@ -14,8 +14,10 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	dqliteNode "github.com/canonical/go-dqlite"
 	dqlite "github.com/canonical/go-dqlite/app"
 	dqliteClient "github.com/canonical/go-dqlite/client"
 	"github.com/canonical/lxd/lxd/db/schema"
@@ -24,6 +26,7 @@ import (
 	"github.com/canonical/lxd/shared/logger"
 	"github.com/canonical/lxd/shared/revert"
 	"github.com/canonical/lxd/shared/tcp"
+	"gopkg.in/yaml.v3"
 
 	"github.com/canonical/microcluster/v3/cluster"
 	"github.com/canonical/microcluster/v3/internal/db/update"
@@ -60,13 +63,59 @@ type DqliteDB struct {
 
 	statusLock sync.RWMutex
 	status     types.DatabaseStatus
+
+	upgradeLock         sync.Mutex
+	upgradeStatus       types.MemberStatus
+	upgradeRunning      bool // Whether the upgrade required hook and the SCHEMA_UPDATE executable are running.
+	upgradeRequiredHook func(ctx context.Context) error
+
+	restoreDump string // SQL dump to restore the database from when bootstrapping.
+
+	backupLock        sync.Mutex
+	lastBackupAttempt time.Time
+	lastBackupError   string
+
+	watchLock    sync.Mutex
+	watchers     map[*watcher]bool
+	watchRunning bool          // Whether changes are being dispatched to watchers.
+	watchLastID  int64         // ID of the last change dispatched to watchers.
+	watchCh      chan struct{} // Notifies the dispatcher of changes to deliver to watchers.
+
+	changeLock        sync.Mutex
+	changeHook        func(ctx context.Context) error // Notifies other cluster members of committed changes.
+	changeHookQueued  atomic.Bool                     // Whether the other cluster members must be notified again.
+	changeHookRunning atomic.Bool                     // Whether the other cluster members are being notified.
+
+	readSlots chan struct{} // Limits the number of concurrent read-only transactions.
+
+	slowLock         sync.Mutex
+	slowThreshold    time.Duration // Duration above which transactions are recorded as slow, or zero if disabled.
+	slowTransactions []types.DatabaseSlowTransaction
 }
 
 const (
 	// DefaultHeartbeatInterval is the default interval used for heartbeats and dqlite role probes.
 	DefaultHeartbeatInterval time.Duration = time.Second * 10
+
+	// DefaultHeartbeatSuspectThreshold is the default number of consecutive missed heartbeats after which a cluster member is considered suspect.
+	DefaultHeartbeatSuspectThreshold int = 1
+
+	// DefaultHeartbeatOfflineThreshold is the default number of consecutive missed heartbeats after which a cluster member is considered offline.
+	DefaultHeartbeatOfflineThreshold int = 3
+
+	// DefaultAutoEvictMinVoters is the default minimum number of voters that must remain online after a cluster member is automatically evicted.
+	DefaultAutoEvictMinVoters int = 1
+
+	// DefaultBackupKeepLast is the default number of most recent scheduled backups to keep.
+	DefaultBackupKeepLast int = 7
+
+	// DefaultReadConns is the default number of read-only transactions that can run concurrently.
+	DefaultReadConns int = 4
 )
 
+// RolesConfig is the target number of voters and stand-bys that dqlite's automatic roles adjustment maintains.
+var RolesConfig = dqlite.RolesConfig{Voters: 3, StandBys: 3}
+
 // Accept sends the outbound connection through the acceptCh channel to be received by dqlite.
 func (db *DqliteDB) Accept(conn net.Conn) {
 	db.acceptCh <- conn
@@ -88,6 +137,8 @@ func NewDB(ctx context.Context, serverCert func() *shared.CertInfo, clusterCert
 		os:                os,
 		acceptCh:          make(chan net.Conn),
 		upgradeCh:         make(chan struct{}),
+		watchCh:           make(chan struct{}, 1),
+		readSlots:         make(chan struct{}, DefaultReadConns),
 		heartbeatInterval: heartbeatInterval,
 		ctx:               shutdownCtx,
 		cancel:            shutdownCancel,
@@ -117,13 +168,7 @@ func (db *DqliteDB) SchemaVersion() (versionInternal uint64, versionExternal uin
 func (db *DqliteDB) Bootstrap(extensions extensions.Extensions, project string, addr api.URL, clusterRecord cluster.CoreClusterMember) error {
 	var err error
 	db.listenAddr = addr
-	db.dqlite, err = dqlite.New(db.os.DatabaseDir,
-		dqlite.WithAddress(db.listenAddr.URL.Host),
-		dqlite.WithRolesAdjustmentFrequency(db.heartbeatInterval),
-		dqlite.WithRolesAdjustmentHook(db.heartbeat),
-		dqlite.WithConcurrentLeaderConns(&db.maxConns),
-		dqlite.WithExternalConn(db.dialFunc(), db.acceptCh),
-		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)))
+	db.dqlite, err = dqlite.New(db.os.DatabaseDir, db.dqliteOptions()...)
 	if err != nil {
 		return fmt.Errorf("Failed to bootstrap dqlite: %w", err)
 	}
@@ -136,7 +181,17 @@ func (db *DqliteDB) Bootstrap(extensions extensions.Extensions, project string,
 	// Apply initial API extensions on the bootstrap node.
 	clusterRecord.APIExtensions = extensions
 	err = db.Transaction(db.ctx, func(ctx context.Context, tx *sql.Tx) error {
-		_, err := cluster.CreateCoreClusterMember(ctx, tx, clusterRecord)
+		// If the database was restored from a backup, the record for this cluster member may already exist.
+		exists, err := cluster.CoreClusterMemberExists(ctx, tx, clusterRecord.Name)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return cluster.UpdateCoreClusterMember(ctx, tx, clusterRecord.Name, clusterRecord)
+		}
+
+		_, err = cluster.CreateCoreClusterMember(ctx, tx, clusterRecord)
 
 		return err
 	})
@@ -151,14 +206,7 @@ func (db *DqliteDB) Bootstrap(extensions extensions.Extensions, project string,
 func (db *DqliteDB) Join(extensions extensions.Extensions, project string, addr api.URL, joinAddresses ...string) error {
 	var err error
 	db.listenAddr = addr
-	db.dqlite, err = dqlite.New(db.os.DatabaseDir,
-		dqlite.WithCluster(joinAddresses),
-		dqlite.WithRolesAdjustmentFrequency(db.heartbeatInterval),
-		dqlite.WithRolesAdjustmentHook(db.heartbeat),
-		dqlite.WithAddress(db.listenAddr.URL.Host),
-		dqlite.WithConcurrentLeaderConns(&db.maxConns),
-		dqlite.WithExternalConn(db.dialFunc(), db.acceptCh),
-		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)))
+	db.dqlite, err = dqlite.New(db.os.DatabaseDir, db.dqliteOptions(dqlite.WithCluster(joinAddresses))...)
 	if err != nil {
 		return fmt.Errorf("Failed to join dqlite cluster %w", err)
 	}
@@ -192,6 +240,106 @@ func (db *DqliteDB) StartWithCluster(extensions extensions.Extensions, project s
 	return db.Join(extensions, project, addr, allClusterAddrs...)
 }
 
+// UpdateAddress restarts dqlite on the given address.
+// The leader is expected to have already recorded the new address for this member in the dqlite cluster configuration,
+// unless this member is the only one in the cluster, in which case the configuration is rewritten locally.
+func (db *DqliteDB) UpdateAddress(extensions extensions.Extensions, project string, addr api.URL) error {
+	db.statusLock.Lock()
+	db.status = types.DatabaseStarting
+	db.statusLock.Unlock()
+
+	if db.db != nil {
+		err := db.db.Close()
+		if err != nil {
+			logger.Error("Failed to close database", logger.Ctx{"address": db.listenAddr.String(), "error": err})
+		}
+
+		db.db = nil
+	}
+
+	err := db.dqlite.Close()
+	if err != nil {
+		return fmt.Errorf("Failed to stop dqlite: %w", err)
+	}
+
+	// dqlite refuses to start if its local records don't match the given address, so update them first.
+	infoPath := filepath.Join(db.os.DatabaseDir, "info.yaml")
+	data, err := os.ReadFile(infoPath)
+	if err != nil {
+		return fmt.Errorf("Failed to read dqlite node info: %w", err)
+	}
+
+	info := dqliteClient.NodeInfo{}
+	err = yaml.Unmarshal(data, &info)
+	if err != nil {
+		return fmt.Errorf("Failed to parse dqlite node info: %w", err)
+	}
+
+	info.Address = addr.URL.Host
+	data, err = yaml.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal dqlite node info: %w", err)
+	}
+
+	err = os.WriteFile(infoPath, data, 0644)
+	if err != nil {
+		return fmt.Errorf("Failed to write dqlite node info: %w", err)
+	}
+
+	store, err := dqliteClient.NewYamlNodeStore(filepath.Join(db.os.DatabaseDir, "cluster.yaml"))
+	if err != nil {
+		return fmt.Errorf("Failed to open dqlite node store: %w", err)
+	}
+
+	nodes, err := store.Get(db.ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to read dqlite node store: %w", err)
+	}
+
+	for i, node := range nodes {
+		if node.ID == info.ID {
+			nodes[i].Address = addr.URL.Host
+		}
+	}
+
+	// Without other members, no leader can record the new address in the raft configuration, so force it instead.
+	if len(nodes) == 1 && nodes[0].ID == info.ID {
+		err = dqliteNode.ReconfigureMembershipExt(db.os.DatabaseDir, []dqliteClient.NodeInfo{{ID: info.ID, Address: addr.URL.Host, Role: dqliteClient.Voter}})
+		if err != nil {
+			return fmt.Errorf("Failed to update dqlite configuration: %w", err)
+		}
+	}
+
+	err = store.Set(db.ctx, nodes)
+	if err != nil {
+		return fmt.Errorf("Failed to update dqlite node store: %w", err)
+	}
+
+	db.listenAddr = addr
+	db.dqlite, err = dqlite.New(db.os.DatabaseDir, db.dqliteOptions()...)
+	if err != nil {
+		return fmt.Errorf("Failed to restart dqlite: %w", err)
+	}
+
+	return db.Open(extensions, false, project)
+}
+
+// dqliteOptions returns the options used to start dqlite on this cluster member, followed by any extra options.
+func (db *DqliteDB) dqliteOptions(extra ...dqlite.Option) []dqlite.Option {
+	options := []dqlite.Option{
+		dqlite.WithAddress(db.listenAddr.URL.Host),
+		dqlite.WithRolesAdjustmentFrequency(db.heartbeatInterval),
+		dqlite.WithRolesAdjustmentHook(db.heartbeat),
+		dqlite.WithVoters(RolesConfig.Voters),
+		dqlite.WithStandBys(RolesConfig.StandBys),
+		dqlite.WithConcurrentLeaderConns(&db.maxConns),
+		dqlite.WithExternalConn(db.dialFunc(), db.acceptCh),
+		dqlite.WithUnixSocket(os.Getenv(sys.DqliteSocket)),
+	}
+
+	return append(options, extra...)
+}
+
 // Leader returns a client connected to the leader of the dqlite cluster.
 func (db *DqliteDB) Leader(ctx context.Context) (*dqliteClient.Client, error) {
 	// Always only try one connection at a time when fetching the leader manually, as this can be an expensive call.
@@ -301,13 +449,20 @@ func (db *DqliteDB) GetHeartbeatInterval() time.Duration {
 }
 
 // SendHeartbeat initiates a new heartbeat sequence if this is a leader node.
-func (db *DqliteDB) SendHeartbeat(ctx context.Context, c *internalClient.Client, hbInfo internalTypes.HeartbeatInfo) error {
+// Returns the version and heartbeat payload reported by the cluster member in its response.
+func (db *DqliteDB) SendHeartbeat(ctx context.Context, c *internalClient.Client, hbInfo internalTypes.HeartbeatInfo) (*internalTypes.HeartbeatResponse, error) {
 	// set the heartbeat timeout to twice the heartbeat interval.
 	heartbeatTimeout := db.heartbeatInterval * 2
 	queryCtx, cancel := context.WithTimeout(ctx, heartbeatTimeout)
 	defer cancel()
 
-	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("heartbeat"), hbInfo, nil)
+	var hbResp internalTypes.HeartbeatResponse
+	err := c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("heartbeat"), hbInfo, &hbResp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &hbResp, nil
 }
 
 func (db *DqliteDB) heartbeat(leaderInfo dqliteClient.NodeInfo, servers []dqliteClient.NodeInfo) error {
@@ -342,7 +497,7 @@ func (db *DqliteDB) heartbeat(leaderInfo dqliteClient.NodeInfo, servers []dqlite
 		hbInfo.DqliteRoles[server.Address] = server.Role.String()
 	}
 
-	err = db.SendHeartbeat(db.ctx, client, hbInfo)
+	_, err = db.SendHeartbeat(db.ctx, client, hbInfo)
 	if err != nil && err.Error() != "Attempt to initiate heartbeat from non-leader" {
 		logger.Error("Failed to initiate heartbeat round", logger.Ctx{"address": db.dqlite.Address(), "error": err})
 		return nil
@@ -433,7 +588,8 @@ func dqliteNetworkDial(ctx context.Context, addr string, db *DqliteDB) (net.Conn
 	// If the remote server has detected that we are out of date, let's
 	// trigger an upgrade.
 	if response.StatusCode == http.StatusUpgradeRequired {
-		// TODO: trigger update.
+		db.RequireUpgrade(fmt.Sprintf("Cluster member %q requires a newer version", addr))
+
 		return nil, fmt.Errorf("Upgrade needed")
 	}
 
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/internal/db/update"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// maxSlowTransactions is the number of most recent slow transactions kept in memory.
+const maxSlowTransactions = 20
+
+// SetSlowTransactionThreshold sets the duration above which transactions are recorded as slow.
+// Slow transactions are not recorded if the threshold is zero.
+func (db *DqliteDB) SetSlowTransactionThreshold(threshold time.Duration) {
+	db.slowLock.Lock()
+	defer db.slowLock.Unlock()
+
+	db.slowThreshold = threshold
+}
+
+// SlowTransactions returns the most recent slow transactions, from oldest to newest.
+func (db *DqliteDB) SlowTransactions() []types.DatabaseSlowTransaction {
+	db.slowLock.Lock()
+	defer db.slowLock.Unlock()
+
+	slowTransactions := make([]types.DatabaseSlowTransaction, len(db.slowTransactions))
+	copy(slowTransactions, db.slowTransactions)
+
+	return slowTransactions
+}
+
+// recordTransaction logs and keeps track of the transaction started at the given time if it took longer than the slow
+// transaction threshold. It must be called directly by the method that ran the transaction, so that its caller can
+// be recorded.
+func (db *DqliteDB) recordTransaction(start time.Time, err error) {
+	duration := time.Since(start)
+
+	db.slowLock.Lock()
+	threshold := db.slowThreshold
+	db.slowLock.Unlock()
+
+	if threshold <= 0 || duration < threshold {
+		return
+	}
+
+	slowTransaction := types.DatabaseSlowTransaction{
+		Time:     start,
+		Duration: duration,
+		Caller:   transactionCaller(),
+	}
+
+	if err != nil {
+		slowTransaction.Error = err.Error()
+	}
+
+	logger.Warn("Slow database transaction", logger.Ctx{"duration": duration, "caller": slowTransaction.Caller, "error": err})
+
+	db.slowLock.Lock()
+	defer db.slowLock.Unlock()
+
+	db.slowTransactions = append(db.slowTransactions, slowTransaction)
+	if len(db.slowTransactions) > maxSlowTransactions {
+		db.slowTransactions = db.slowTransactions[len(db.slowTransactions)-maxSlowTransactions:]
+	}
+}
+
+// transactionCaller returns the name and source location of the function which ran a transaction.
+func transactionCaller() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+
+		// Skip the transaction methods themselves, and any method value wrappers around them.
+		isTransaction := strings.HasSuffix(frame.Function, ".(*DqliteDB).Transaction") || strings.HasSuffix(frame.Function, ".(*DqliteDB).ReadTransaction")
+		if !isTransaction && !strings.HasSuffix(frame.Function, "-fm") {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line)
+		}
+
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// Info returns diagnostic information about the database: the dqlite raft configuration, the size of the database,
+// and the number of rows in each of its tables. Only the status is available until the database has been opened.
+func (db *DqliteDB) Info(ctx context.Context) (*types.DatabaseInfo, error) {
+	info := &types.DatabaseInfo{
+		Status:           db.Status(),
+		Nodes:            []types.DatabaseNode{},
+		Tables:           []types.DatabaseTable{},
+		SlowTransactions: db.SlowTransactions(),
+	}
+
+	if info.Status != types.DatabaseReady && info.Status != types.DatabaseWaiting {
+		return info, nil
+	}
+
+	info.NodeID = db.dqlite.ID()
+	leaderClient, err := db.Leader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get dqlite leader: %w", err)
+	}
+
+	defer func() { _ = leaderClient.Close() }()
+
+	leader, err := leaderClient.Leader(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get dqlite leader information: %w", err)
+	}
+
+	if leader != nil {
+		info.Leader = leader.Address
+	}
+
+	nodes, err := db.Cluster(ctx, leaderClient)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range nodes {
+		info.Nodes = append(info.Nodes, types.DatabaseNode{ID: node.ID, Address: node.Address, Role: node.Role.String()})
+	}
+
+	err = db.ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return getTableInfo(ctx, tx, info)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// getTableInfo fills in the page counts of the database, and the row counts of each of its tables.
+func getTableInfo(ctx context.Context, tx *sql.Tx, info *types.DatabaseInfo) error {
+	pragmas := map[string]*int64{
+		"page_size":      &info.PageSize,
+		"page_count":     &info.PageCount,
+		"freelist_count": &info.FreePageCount,
+	}
+
+	for pragma, value := range pragmas {
+		err := tx.QueryRowContext(ctx, "PRAGMA "+pragma).Scan(value)
+		if err != nil {
+			return fmt.Errorf("Failed to get database %s: %w", pragma, err)
+		}
+	}
+
+	info.Size = info.PageSize * info.PageCount
+
+	tables, err := query.SelectStrings(ctx, tx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return fmt.Errorf("Failed to get database tables: %w", err)
+	}
+
+	for _, table := range tables {
+		var rows int64
+		err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+update.QuoteIdentifier(table)).Scan(&rows)
+		if err != nil {
+			return fmt.Errorf("Failed to count rows of table %q: %w", table, err)
+		}
+
+		info.Tables = append(info.Tables, types.DatabaseTable{Name: table, Rows: rows})
+	}
+
+	return nil
+}
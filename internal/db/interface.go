@@ -15,6 +15,10 @@ type DB interface {
 	// Transaction handles performing a transaction on the dqlite database.
 	Transaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) error
 
+	// ReadTransaction handles performing a read-only transaction on the dqlite database.
+	// Read-only transactions do not queue behind transactions that modify the database.
+	ReadTransaction(outerCtx context.Context, f func(context.Context, *sql.Tx) error) error
+
 	// Leader returns a client connected to the leader of the dqlite cluster.
 	Leader(ctx context.Context) (*dqliteClient.Client, error)
 
@@ -31,4 +35,9 @@ type DB interface {
 
 	// SchemaVersion returns the current internal and external schema version, as well as all API extensions in memory.
 	SchemaVersion() (versionInternal uint64, versionExternal uint64, apiExtensions extensions.Extensions)
+
+	// Watch returns a channel receiving the changes made to the given tables by any cluster member, after each
+	// committed transaction. The channel is closed once the given context is cancelled, or if the watcher falls behind.
+	// Only the tables registered with the WatchedTables daemon argument can be watched.
+	Watch(ctx context.Context, tables ...string) (<-chan types.DatabaseChange, error)
 }
@@ -0,0 +1,68 @@
+package update
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// ensureChangeTriggers creates the triggers recording the changes made to the given tables in the change log, if they
+// do not exist yet.
+func ensureChangeTriggers(ctx context.Context, tx *sql.Tx, tables []string) error {
+	for _, table := range tables {
+		err := createChangeTriggers(ctx, tx, table)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// createChangeTriggers creates the triggers recording the changes made to the given table in the change log, if they
+// do not exist yet. Changes are recorded with the table name as given.
+func createChangeTriggers(ctx context.Context, tx *sql.Tx, table string) error {
+	var name string
+	err := tx.QueryRowContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table' AND name = ? COLLATE NOCASE", table).Scan(&name)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("Cannot watch table %q as it does not exist", table)
+	} else if err != nil {
+		return fmt.Errorf("Failed to look up table %q: %w", table, err)
+	}
+
+	if strings.EqualFold(name, "core_change_log") || strings.HasPrefix(strings.ToLower(name), "sqlite_") {
+		return fmt.Errorf("Table %q cannot be watched", name)
+	}
+
+	operations := map[types.DatabaseChangeOperation]string{
+		types.DatabaseChangeInsert: "NEW",
+		types.DatabaseChangeUpdate: "NEW",
+		types.DatabaseChangeDelete: "OLD",
+	}
+
+	for operation, row := range operations {
+		stmt := fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %s AFTER %s ON %s BEGIN
+  INSERT INTO core_change_log (table_name, operation, row_id) VALUES (%s, '%s', %s.rowid);
+END`, QuoteIdentifier("core_change_log_"+table+"_"+string(operation)), strings.ToUpper(string(operation)), QuoteIdentifier(name), quoteString(table), operation, row)
+
+		_, err := tx.ExecContext(ctx, stmt)
+		if err != nil {
+			return fmt.Errorf("Failed to create %s trigger on table %q: %w", operation, name, err)
+		}
+	}
+
+	return nil
+}
+
+// QuoteIdentifier quotes the given SQL identifier, such as a table name.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteString quotes the given value as a SQL string literal.
+func quoteString(value string) string {
+	return `'` + strings.ReplaceAll(value, `'`, `''`) + `'`
+}
@@ -9,6 +9,7 @@ import (
 	"github.com/canonical/lxd/shared/logger"
 
 	"github.com/canonical/microcluster/v3/internal/extensions"
+	"github.com/canonical/microcluster/v3/rest/types"
 )
 
 // PrepareUpdateV1 creates the temporary table `internal_cluster_members_new` if we have not yet run `updateFromV1`.
@@ -184,6 +185,92 @@ WHERE name IN ('api_extensions');
 	return nil
 }
 
+// UpdateClusterMemberUpgradeStatus sets the upgrade status of the cluster member with the given name.
+// This helper is non-generated to work before generated statements are loaded, as we may be behind the cluster's schema.
+func UpdateClusterMemberUpgradeStatus(ctx context.Context, tx *sql.Tx, status string, memberName string) error {
+	table, err := getClusterTableName(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// Check for the `upgrade_status` column, which may not exist if we haven't actually run the update yet.
+	stmt := fmt.Sprintf(`
+SELECT count(name)
+FROM pragma_table_info('%s')
+WHERE name IN ('upgrade_status');
+`, table)
+
+	var count int
+	err = tx.QueryRow(stmt).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		logger.Warn("Skipping upgrade status update, schema does not yet support it", logger.Ctx{"memberName": memberName})
+		return nil
+	}
+
+	stmt = fmt.Sprintf("UPDATE %s SET upgrade_status=? WHERE name=?", table)
+	result, err := tx.ExecContext(ctx, stmt, status, memberName)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return fmt.Errorf("Updated %d rows instead of 1", n)
+	}
+
+	return nil
+}
+
+// UpdateClusterMemberVersion sets the version of the project running on the cluster member with the given name.
+// This helper is non-generated to work before generated statements are loaded, as we may be waiting for an upgrade.
+func UpdateClusterMemberVersion(ctx context.Context, tx *sql.Tx, version string, memberName string) error {
+	table, err := getClusterTableName(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// Check for the `version` column, which may not exist if we haven't actually run the update yet.
+	stmt := fmt.Sprintf(`
+SELECT count(name)
+FROM pragma_table_info('%s')
+WHERE name IN ('version');
+`, table)
+
+	var count int
+	err = tx.QueryRow(stmt).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		logger.Warn("Skipping version update, schema does not yet support it", logger.Ctx{"memberName": memberName})
+		return nil
+	}
+
+	stmt = fmt.Sprintf("UPDATE %s SET version=? WHERE name=?", table)
+	result, err := tx.ExecContext(ctx, stmt, version, memberName)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return fmt.Errorf("Updated %d rows instead of 1", n)
+	}
+
+	return nil
+}
+
 // GetClusterMemberAPIExtensions returns the API extensions from all cluster members that are not pending.
 // This helper is non-generated to work before generated statements are loaded, as we update the API extensions.
 func GetClusterMemberAPIExtensions(ctx context.Context, tx *sql.Tx) ([]extensions.Extensions, error) {
@@ -224,6 +311,145 @@ func GetClusterMemberAPIExtensions(ctx context.Context, tx *sql.Tx) ([]extension
 	return results, nil
 }
 
+// GetClusterMemberSchemas returns the schema versions supported by all cluster members, sorted by name.
+// This helper is non-generated to work before generated statements are loaded, as the schema may not be up to date.
+func GetClusterMemberSchemas(ctx context.Context, tx *sql.Tx) ([]types.DatabaseSchemaMember, error) {
+	table, err := getClusterTableName(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	hasTypes, err := hasSchemaTypes(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Before updateFromV1, a single schema version was recorded, as is converted by PrepareUpdateV1.
+	stmt := fmt.Sprintf("SELECT name, role, schema_internal, schema_external FROM %s ORDER BY name", table)
+	if !hasTypes {
+		stmt = fmt.Sprintf("SELECT name, role, 1, (schema - 1) FROM %s ORDER BY name", table)
+	}
+
+	members := []types.DatabaseSchemaMember{}
+	err = query.Scan(ctx, tx, stmt, func(scan func(dest ...any) error) error {
+		member := types.DatabaseSchemaMember{}
+		err := scan(&member.Name, &member.Role, &member.SchemaInternalVersion, &member.SchemaExternalVersion)
+		if err != nil {
+			return err
+		}
+
+		members = append(members, member)
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get cluster member schema versions: %w", err)
+	}
+
+	return members, nil
+}
+
+// GetClusterMemberVersion returns the version of the project last recorded by the cluster member with the given name,
+// or an empty string if the schema does not yet record it.
+// This helper is non-generated to work before generated statements are loaded, as we may be waiting for an upgrade.
+func GetClusterMemberVersion(ctx context.Context, tx *sql.Tx, memberName string) (string, error) {
+	table, err := getClusterTableName(ctx, tx)
+	if err != nil {
+		return "", err
+	}
+
+	hasVersion, err := hasColumn(ctx, tx, table, "version")
+	if err != nil || !hasVersion {
+		return "", err
+	}
+
+	var version string
+	err = tx.QueryRowContext(ctx, fmt.Sprintf("SELECT version FROM %s WHERE name=?", table), memberName).Scan(&version)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	return version, nil
+}
+
+// GetBackupClusterMembers returns the records of all cluster members, as recorded in the metadata of a database backup.
+// This helper is non-generated to work before generated statements are loaded, as we may be waiting for an upgrade.
+func GetBackupClusterMembers(ctx context.Context, tx *sql.Tx) ([]types.DatabaseBackupMember, error) {
+	table, err := getClusterTableName(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []types.DatabaseBackupMember{}
+	err = query.Scan(ctx, tx, fmt.Sprintf("SELECT name, address, role FROM %s ORDER BY name", table), func(scan func(dest ...any) error) error {
+		var address string
+		member := types.DatabaseBackupMember{}
+		err := scan(&member.Name, &address, &member.Role)
+		if err != nil {
+			return err
+		}
+
+		member.Address, err = types.ParseAddrPort(address)
+		if err != nil {
+			return fmt.Errorf("Failed to parse address %q of cluster member %q: %w", address, member.Name, err)
+		}
+
+		members = append(members, member)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// ResetClusterMemberVersions sets the schema versions of all cluster members to the given ones, and clears their API
+// extensions, so that the database can be restored by a cluster member running at those schema versions.
+// This helper is non-generated to work before generated statements are loaded, as we may be waiting for an upgrade.
+func ResetClusterMemberVersions(ctx context.Context, tx *sql.Tx, internalVersion uint64, externalVersion uint64) error {
+	hasTypes, err := hasSchemaTypes(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	// Databases from before updateFromV1 can't be restored regardless.
+	if !hasTypes {
+		return nil
+	}
+
+	table, err := getClusterTableName(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET schema_internal=?,schema_external=?", table), internalVersion, externalVersion)
+	if err != nil {
+		return err
+	}
+
+	hasAPIExtensions, err := hasColumn(ctx, tx, table, "api_extensions")
+	if err != nil || !hasAPIExtensions {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET api_extensions=?", table), extensions.Extensions{})
+
+	return err
+}
+
+// hasColumn returns whether the given table has a column with the given name.
+func hasColumn(ctx context.Context, tx *sql.Tx, table string, column string) (bool, error) {
+	var count int
+	err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT count(name) FROM pragma_table_info('%s') WHERE name = ?", table), column).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count == 1, nil
+}
+
 // getClusterTableName returns the name of the table that holds the record of cluster members from sqlite_master.
 // Prior to updateFromV4, this table was called `internal_cluster_members`, but now it is `core_cluster_members`.
 // Since we need to check this table to perform the update that renames it, we can use this function to dynamically determine its name.
@@ -240,3 +466,80 @@ func getClusterTableName(ctx context.Context, tx *sql.Tx) (string, error) {
 
 	return tables[0], nil
 }
+
+// RestoreClusterMembers prepares a database restored from a backup for use by a single cluster member.
+// It checks that no cluster member in the backup is ahead of the given schema versions and API extensions, and then
+// removes every cluster member other than the one with the given name, along with any join tokens of the old cluster.
+// This helper is non-generated to work before generated statements are loaded, as the backup may predate the current schema.
+func RestoreClusterMembers(ctx context.Context, tx *sql.Tx, schemaInternal uint64, schemaExternal uint64, apiExtensions extensions.Extensions, memberName string) error {
+	stmt := "SELECT count(name) FROM pragma_table_info('schemas') WHERE name IN ('type');"
+	var count int
+	err := tx.QueryRowContext(ctx, stmt).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count != 1 {
+		return fmt.Errorf("Backup predates the oldest supported schema")
+	}
+
+	table, err := getClusterTableName(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	versionsInternal, versionsExternal, err := GetClusterMemberSchemaVersions(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for i := range versionsInternal {
+		if versionsInternal[i] > schemaInternal || versionsExternal[i] > schemaExternal {
+			return fmt.Errorf("Backup schema version %d.%d is ahead of this cluster member's %d.%d, please upgrade", versionsInternal[i], versionsExternal[i], schemaInternal, schemaExternal)
+		}
+	}
+
+	// Check for the `api_extensions` column, which may not exist if the backup predates it.
+	stmt = fmt.Sprintf("SELECT count(name) FROM pragma_table_info('%s') WHERE name IN ('api_extensions');", table)
+	err = tx.QueryRowContext(ctx, stmt).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count == 1 {
+		clusterMemberAPIExtensions, err := GetClusterMemberAPIExtensions(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, memberExtensions := range clusterMemberAPIExtensions {
+			for _, extension := range memberExtensions {
+				if !apiExtensions.HasExtension(extension) {
+					return fmt.Errorf("Backup requires API extension %q which this cluster member does not support, please upgrade", extension)
+				}
+			}
+		}
+	}
+
+	stmt = fmt.Sprintf("DELETE FROM %s WHERE name != ?", table)
+	_, err = tx.ExecContext(ctx, stmt, memberName)
+	if err != nil {
+		return fmt.Errorf("Failed to remove cluster members of the backup: %w", err)
+	}
+
+	// Prior to updateFromV4, the token records table was called `internal_token_records`.
+	stmt = "SELECT name FROM sqlite_master WHERE name = 'internal_token_records' OR name = 'core_token_records'"
+	tables, err := query.SelectStrings(ctx, tx, stmt)
+	if err != nil {
+		return err
+	}
+
+	for _, tokensTable := range tables {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", tokensTable))
+		if err != nil {
+			return fmt.Errorf("Failed to remove join tokens of the backup: %w", err)
+		}
+	}
+
+	return nil
+}
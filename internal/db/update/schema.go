@@ -3,14 +3,18 @@ package update
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"os"
+	"time"
 
 	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/lxd/db/schema"
 	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/logger"
 
 	"github.com/canonical/microcluster/v3/internal/extensions"
+	"github.com/canonical/microcluster/v3/rest/types"
 )
 
 // updateType represents whether the update is an internal or external schema update.
@@ -24,6 +28,12 @@ const (
 	updateExternal updateType = 1
 )
 
+// updateTypes maps each type of schema update to its API representation, in the order updates are applied.
+var updateTypes = []types.DatabaseSchemaUpdateType{
+	updateInternal: types.DatabaseSchemaInternal,
+	updateExternal: types.DatabaseSchemaExternal,
+}
+
 // SchemaUpdate holds the configuration for executing schema updates.
 type SchemaUpdate struct {
 	updates       map[updateType][]schema.Update // Ordered series of internal and external updates making up the schema
@@ -31,9 +41,15 @@ type SchemaUpdate struct {
 	hook          schema.Hook  // Optional hook to execute whenever a update gets applied
 	fresh         string       // Optional SQL statement used to create schema from scratch
 	check         schema.Check // Optional callback invoked before doing any update
+	snapshot      SnapshotFunc // Optional callback invoked before applying updates to an existing database
+	watchedTables []string     // Optional tables whose changes are recorded in the change log
 	path          string       // Optional path to a file containing extra queries to run
 }
 
+// SnapshotFunc is a function taking a copy of the database before schema updates are applied to it. It is given the
+// internal and external schema versions of the database.
+type SnapshotFunc func(ctx context.Context, tx *sql.Tx, internalVersion uint64, externalVersion uint64) error
+
 // Fresh sets a statement that will be used to create the schema from scratch
 // when bootstraping an empty database. It should be a "flattening" of the
 // available updates, generated using the Dump() method. If not given, all
@@ -55,6 +71,25 @@ func (s *SchemaUpdate) Check(check schema.Check) {
 	s.check = check
 }
 
+// Snapshot instructs the schema to invoke the given function whenever Ensure
+// is about to apply updates to an existing database, after the check. If it
+// fails, no updates are applied.
+func (s *SchemaUpdate) Snapshot(snapshot SnapshotFunc) {
+	s.snapshot = snapshot
+}
+
+// WatchTables sets the tables whose changes are recorded in the change log, so that they can be watched. Whenever
+// Ensure is invoked, it creates the triggers recording their changes once all updates are applied, including after an
+// update re-creates a watched table.
+func (s *SchemaUpdate) WatchTables(tables []string) {
+	s.watchedTables = tables
+}
+
+// WatchedTables returns the tables whose changes are recorded in the change log.
+func (s *SchemaUpdate) WatchedTables() []string {
+	return s.watchedTables
+}
+
 // Version returns the internal and external schema update versions, corresponding to the number of updates that have occurred.
 func (s *SchemaUpdate) Version() (internalVersion uint64, externalVersion uint64, apiExtensions extensions.Extensions) {
 	return uint64(len(s.updates[updateInternal])), uint64(len(s.updates[updateExternal])), s.apiExtensions
@@ -180,6 +215,17 @@ func (s *SchemaUpdate) Ensure(db *sql.DB) (int, error) {
 		return current, schema.ErrGracefulAbort
 	}
 
+	// Let a copy of the database be taken before any update is applied to it.
+	hasUpdates := versions[updateInternal] < len(s.updates[updateInternal]) || versions[updateExternal] < len(s.updates[updateExternal])
+	if exists && hasUpdates && s.snapshot != nil {
+		err = query.Transaction(context.TODO(), db, func(ctx context.Context, tx *sql.Tx) error {
+			return s.snapshot(ctx, tx, uint64(versions[updateInternal]), uint64(versions[updateExternal]))
+		})
+		if err != nil {
+			return -1, fmt.Errorf("Failed to snapshot the database before applying schema updates: %w", err)
+		}
+	}
+
 	// If there are internal schema updates to run, ensure foreign keys are disabled
 	// so any external tables that reference internal ones are not wiped.
 	hasInternaUpdates := versions[updateInternal] < len(s.updates[updateInternal])
@@ -242,7 +288,7 @@ func (s *SchemaUpdate) Ensure(db *sql.DB) (int, error) {
 			}
 		}
 
-		return nil
+		return ensureChangeTriggers(ctx, tx, s.watchedTables)
 	})
 	if err != nil {
 		return -1, err
@@ -251,6 +297,215 @@ func (s *SchemaUpdate) Ensure(db *sql.DB) (int, error) {
 	return current, nil
 }
 
+// Status returns the schema updates that have been applied to the database, and the updates of this schema that have
+// yet to be applied.
+func (s *SchemaUpdate) Status(ctx context.Context, tx *sql.Tx) (applied []types.DatabaseSchemaUpdate, pending []types.DatabaseSchemaUpdate, err error) {
+	applied, err = getAppliedUpdates(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return applied, s.pendingUpdates(appliedVersions(applied)), nil
+}
+
+// DryRun applies the pending schema updates to the database in a transaction which is then rolled back, leaving the
+// database unchanged. It returns the updates that were applied, or the error of the first update that failed.
+//
+// Unlike Ensure, the check is not invoked, no queries are run from the file set with File, and internal and external
+// updates are applied in the same transaction. If there are internal updates to apply, foreign keys are disabled
+// throughout.
+func (s *SchemaUpdate) DryRun(ctx context.Context, db *sql.DB) ([]types.DatabaseSchemaUpdate, error) {
+	// Use a single connection so that the pragmas set below apply to the transaction.
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	var pending []types.DatabaseSchemaUpdate
+	err = rolledBackTransaction(ctx, conn, func(ctx context.Context, tx *sql.Tx) error {
+		applied, err := getAppliedUpdates(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		pending = s.pendingUpdates(appliedVersions(applied))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pending) == 0 {
+		return pending, nil
+	}
+
+	// As with Ensure, disable foreign keys before applying internal updates, so that any external tables that
+	// reference internal ones are not wiped. The pragma has no effect within a transaction.
+	if pending[0].Type == types.DatabaseSchemaInternal {
+		defer func() {
+			_, err := conn.ExecContext(context.Background(), "PRAGMA foreign_keys=ON; PRAGMA legacy_alter_table=OFF")
+			if err != nil {
+				logger.Error("Failed to re-enable foreign keys after schema update dry run, discarding connection", logger.Ctx{"error": err})
+
+				// Returning driver.ErrBadConn removes the connection from the pool instead of returning it without foreign keys.
+				_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			}
+		}()
+
+		_, err = conn.ExecContext(ctx, "PRAGMA foreign_keys=OFF; PRAGMA legacy_alter_table=ON")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = rolledBackTransaction(ctx, conn, func(ctx context.Context, tx *sql.Tx) error {
+		// Fix the schemas table first if needed, as Ensure does once all cluster members have settled.
+		hasTypes, err := hasSchemaTypes(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		if !hasTypes {
+			err = updateFromV1(ctx, tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		applied, err := getAppliedUpdates(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		versions := appliedVersions(applied)
+		if versions[updateExternal] == 0 && versions[updateInternal] == 0 && s.fresh != "" {
+			_, err = tx.ExecContext(ctx, s.fresh)
+			if err != nil {
+				return fmt.Errorf("Cannot apply fresh schema: %w", err)
+			}
+
+			return ensureChangeTriggers(ctx, tx, s.watchedTables)
+		}
+
+		for t, version := range versions {
+			err = ensureUpdatesAreApplied(ctx, tx, updateType(t), version, s.updates[updateType(t)], s.hook)
+			if err != nil {
+				return fmt.Errorf("Failed to apply %s schema updates: %w", updateTypes[t], err)
+			}
+		}
+
+		return ensureChangeTriggers(ctx, tx, s.watchedTables)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// pendingUpdates returns the updates of this schema that come after the given internal and external versions.
+func (s *SchemaUpdate) pendingUpdates(versions []int) []types.DatabaseSchemaUpdate {
+	pending := []types.DatabaseSchemaUpdate{}
+	for t, version := range versions {
+		for v := version + 1; v <= len(s.updates[updateType(t)]); v++ {
+			pending = append(pending, types.DatabaseSchemaUpdate{Type: updateTypes[t], Version: uint64(v)})
+		}
+	}
+
+	return pending
+}
+
+// rolledBackTransaction runs the given function in a transaction on the given connection, and then rolls it back.
+func rolledBackTransaction(ctx context.Context, conn *sql.Conn, f func(context.Context, *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("Failed to begin transaction: %w", err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	return f(ctx, tx)
+}
+
+// getAppliedUpdates returns the schema updates recorded in the schemas table, internal updates first.
+// If the schemas table predates updateFromV1, its records are interpreted as updateFromV1 would.
+func getAppliedUpdates(ctx context.Context, tx *sql.Tx) ([]types.DatabaseSchemaUpdate, error) {
+	applied := []types.DatabaseSchemaUpdate{}
+	exists, err := doesSchemaTableExist(tx)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to check if schema table is there: %w", err)
+	}
+
+	if !exists {
+		return applied, nil
+	}
+
+	hasTypes, err := hasSchemaTypes(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The timestamps are stored as unix seconds, but cast them explicitly as drivers may parse DATETIME columns.
+	stmt := "SELECT type, version, CAST(updated_at AS INTEGER) FROM schemas ORDER BY type, version"
+	if !hasTypes {
+		stmt = "SELECT version > 1, CASE WHEN version = 1 THEN 1 ELSE version - 1 END, CAST(updated_at AS INTEGER) FROM schemas ORDER BY version"
+	}
+
+	err = query.Scan(ctx, tx, stmt, func(scan func(dest ...any) error) error {
+		var t updateType
+		var version uint64
+		var updatedAt int64
+		err := scan(&t, &version, &updatedAt)
+		if err != nil {
+			return err
+		}
+
+		if int(t) >= len(updateTypes) {
+			return fmt.Errorf("Invalid schema update type %d", t)
+		}
+
+		applied = append(applied, types.DatabaseSchemaUpdate{Type: updateTypes[t], Version: version, UpdatedAt: time.Unix(updatedAt, 0).UTC()})
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get applied schema updates: %w", err)
+	}
+
+	return applied, nil
+}
+
+// appliedVersions returns the internal and external schema versions reached by the given applied updates.
+func appliedVersions(applied []types.DatabaseSchemaUpdate) []int {
+	versions := make([]int, len(updateTypes))
+	for _, update := range applied {
+		for t, updateType := range updateTypes {
+			if update.Type == updateType && int(update.Version) > versions[t] {
+				versions[t] = int(update.Version)
+			}
+		}
+	}
+
+	return versions
+}
+
+// hasSchemaTypes returns whether the schemas table records the type of each update, which is the case once
+// updateFromV1 has been applied.
+func hasSchemaTypes(ctx context.Context, tx *sql.Tx) (bool, error) {
+	stmt := "SELECT count(name) FROM pragma_table_info('schemas') WHERE name IN ('type');"
+
+	var count int
+	err := tx.QueryRowContext(ctx, stmt).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count == 1, nil
+}
+
 // Apply any pending update that was not yet applied.
 func ensureUpdatesAreApplied(ctx context.Context, tx *sql.Tx, updateType updateType, version int, updates []schema.Update, hook schema.Hook) error {
 	if version > len(updates) {
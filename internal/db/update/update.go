@@ -38,6 +38,15 @@ func NewSchema() *SchemaUpdateManager {
 			mgr.updateFromV3,
 			updateFromV4,
 			updateFromV5,
+			updateFromV6,
+			updateFromV7,
+			updateFromV8,
+			updateFromV9,
+			updateFromV10,
+			updateFromV11,
+			updateFromV12,
+			updateFromV13,
+			updateFromV14,
 		},
 	}
 
@@ -75,6 +84,118 @@ func (s *SchemaUpdateManager) AppendSchema(schemaExtensions []schema.Update, api
 	s.apiExtensions = apiExtensions
 }
 
+// updateFromV14 adds a table recording the changes made to the tables watched for changes.
+func updateFromV14(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE core_change_log (
+  id          INTEGER   PRIMARY  KEY  AUTOINCREMENT  NOT  NULL,
+  table_name  TEXT      NOT      NULL,
+  operation   TEXT      NOT      NULL,
+  row_id      INTEGER   NOT      NULL,
+  created_at  DATETIME  NOT      NULL  DEFAULT  CURRENT_TIMESTAMP
+);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV13 adds a column to the core_cluster_members table to record the version each member is running.
+func updateFromV13(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_cluster_members ADD COLUMN version TEXT NOT NULL DEFAULT '';
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV12 adds a column to the core_cluster_members table to track the upgrade progress of each member.
+func updateFromV12(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_cluster_members ADD COLUMN upgrade_status TEXT NOT NULL DEFAULT '';
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV11 adds columns to the core_cluster_members table to record the latency and last error of heartbeats.
+func updateFromV11(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_cluster_members ADD COLUMN heartbeat_latency INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE core_cluster_members ADD COLUMN heartbeat_error TEXT NOT NULL DEFAULT '';
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV10 adds a column to the core_cluster_members table to store the payload of each member's last heartbeat.
+func updateFromV10(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_cluster_members ADD COLUMN heartbeat_payload TEXT NOT NULL DEFAULT '{}';
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV9 adds columns to the core_cluster_members table to track consecutive heartbeat failures.
+func updateFromV9(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_cluster_members ADD COLUMN heartbeat_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE core_cluster_members ADD COLUMN heartbeat_status TEXT NOT NULL DEFAULT 'ONLINE';
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV8 adds a labels column to the core_cluster_members table.
+func updateFromV8(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_cluster_members ADD COLUMN labels TEXT NOT NULL DEFAULT '{}';
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV7 adds a table for cluster-wide configuration.
+func updateFromV7(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+CREATE TABLE core_config (
+  id     INTEGER  PRIMARY  KEY    AUTOINCREMENT  NOT  NULL,
+  key    TEXT     NOT      NULL,
+  value  TEXT     NOT      NULL,
+  UNIQUE (key)
+);
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
+// updateFromV6 adds a failure domain column to the core_cluster_members table.
+func updateFromV6(ctx context.Context, tx *sql.Tx) error {
+	stmt := `
+ALTER TABLE core_cluster_members ADD COLUMN failure_domain TEXT NOT NULL DEFAULT '';
+`
+
+	_, err := tx.ExecContext(ctx, stmt)
+
+	return err
+}
+
 // updateFromV5 adds an expiration column for join tokens.
 func updateFromV5(ctx context.Context, tx *sql.Tx) error {
 	stmt := `CREATE TABLE core_token_records_new (
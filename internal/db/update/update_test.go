@@ -10,6 +10,8 @@ import (
 	"github.com/canonical/lxd/lxd/db/query"
 	"github.com/canonical/lxd/lxd/db/schema"
 	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcluster/v3/rest/types"
 )
 
 type updateSuite struct {
@@ -226,6 +228,223 @@ func (s *updateSuite) Test_updateFromV1() {
 	}
 }
 
+// Ensures the schema status lists the applied and pending updates, and that a dry run applies pending updates without
+// changing the database.
+func (s *updateSuite) Test_schemaDryRun() {
+	dummyUpdate := func(ctx context.Context, tx *sql.Tx) error { return nil }
+	createUpdate := func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "CREATE TABLE dry_run (id INTEGER PRIMARY KEY)")
+		return err
+	}
+
+	failedUpdate := func(ctx context.Context, tx *sql.Tx) error { return fmt.Errorf("Failed") }
+
+	tests := []struct {
+		name             string
+		upgradesInternal []schema.Update
+		upgradesExternal []schema.Update
+		expectPending    []types.DatabaseSchemaUpdate
+		expectErr        bool
+	}{
+		{
+			name:          "No pending updates",
+			expectPending: []types.DatabaseSchemaUpdate{},
+		},
+		{
+			name:             "Pending internal and external updates",
+			upgradesInternal: []schema.Update{createUpdate},
+			upgradesExternal: []schema.Update{dummyUpdate, dummyUpdate},
+			expectPending: []types.DatabaseSchemaUpdate{
+				{Type: types.DatabaseSchemaInternal, Version: 3},
+				{Type: types.DatabaseSchemaExternal, Version: 2},
+				{Type: types.DatabaseSchemaExternal, Version: 3},
+			},
+		},
+		{
+			name:             "Pending external update",
+			upgradesExternal: []schema.Update{createUpdate},
+			expectPending: []types.DatabaseSchemaUpdate{
+				{Type: types.DatabaseSchemaExternal, Version: 2},
+			},
+		},
+		{
+			name:             "Failed external update",
+			upgradesExternal: []schema.Update{createUpdate, failedUpdate},
+			expectPending: []types.DatabaseSchemaUpdate{
+				{Type: types.DatabaseSchemaExternal, Version: 2},
+				{Type: types.DatabaseSchemaExternal, Version: 3},
+			},
+			expectErr: true,
+		},
+	}
+
+	for i, t := range tests {
+		s.T().Logf("%s (case %d)", t.name, i)
+
+		schemaMgr := &SchemaUpdateManager{
+			updates: map[updateType][]schema.Update{
+				updateInternal: {updateFromV0, updateFromV1},
+				updateExternal: {dummyUpdate},
+			},
+		}
+
+		db, err := NewTestDBWithSchema(schemaMgr)
+		s.Require().NoError(err)
+
+		// Keep to a single connection, as each one opens its own in-memory database.
+		db.SetMaxOpenConns(1)
+
+		schemaMgr.updates[updateInternal] = append(schemaMgr.updates[updateInternal], t.upgradesInternal...)
+		schemaMgr.updates[updateExternal] = append(schemaMgr.updates[updateExternal], t.upgradesExternal...)
+		newSchema := schemaMgr.Schema()
+
+		ctx := context.Background()
+		getStatus := func() ([]types.DatabaseSchemaUpdate, []types.DatabaseSchemaUpdate) {
+			tx, err := db.BeginTx(ctx, nil)
+			s.Require().NoError(err)
+
+			defer func() { s.NoError(tx.Rollback()) }()
+
+			applied, pending, err := newSchema.Status(ctx, tx)
+			s.Require().NoError(err)
+
+			return applied, pending
+		}
+
+		applied, pending := getStatus()
+		s.Equal(t.expectPending, pending)
+		s.Len(applied, 3)
+		for _, update := range applied {
+			s.WithinDuration(time.Now(), update.UpdatedAt, time.Minute)
+		}
+
+		updates, err := newSchema.DryRun(ctx, db)
+		if t.expectErr {
+			s.Error(err)
+		} else {
+			s.NoError(err)
+			s.Equal(t.expectPending, updates)
+		}
+
+		// The database is left unchanged.
+		newApplied, newPending := getStatus()
+		s.Equal(applied, newApplied)
+		s.Equal(pending, newPending)
+
+		var count int
+		err = db.QueryRowContext(ctx, "SELECT count(name) FROM sqlite_master WHERE name = 'dry_run'").Scan(&count)
+		s.NoError(err)
+		s.Equal(0, count)
+
+		// Foreign keys are enabled again.
+		var foreignKeys int
+		err = db.QueryRowContext(ctx, "PRAGMA foreign_keys").Scan(&foreignKeys)
+		s.NoError(err)
+		s.Equal(1, foreignKeys)
+
+		s.NoError(db.Close())
+	}
+}
+
+// Ensures a snapshot is only taken before updates are applied to an existing database, and that no updates are
+// applied if it fails.
+func (s *updateSuite) Test_ensureSnapshot() {
+	dummyUpdate := func(ctx context.Context, tx *sql.Tx) error { return nil }
+
+	tests := []struct {
+		name             string
+		upgradesInternal []schema.Update
+		upgradesExternal []schema.Update
+		snapshotErr      error
+		expectSnapshot   bool
+	}{
+		{
+			name: "No pending updates",
+		},
+		{
+			name:             "Pending internal update",
+			upgradesInternal: []schema.Update{dummyUpdate},
+			expectSnapshot:   true,
+		},
+		{
+			name:             "Pending external update",
+			upgradesExternal: []schema.Update{dummyUpdate},
+			expectSnapshot:   true,
+		},
+		{
+			name:             "Failed snapshot",
+			upgradesExternal: []schema.Update{dummyUpdate},
+			snapshotErr:      fmt.Errorf("Failed"),
+			expectSnapshot:   true,
+		},
+	}
+
+	for i, t := range tests {
+		s.T().Logf("%s (case %d)", t.name, i)
+
+		schemaMgr := &SchemaUpdateManager{
+			updates: map[updateType][]schema.Update{
+				updateInternal: {updateFromV0, updateFromV1},
+				updateExternal: {dummyUpdate},
+			},
+		}
+
+		db, err := sql.Open("sqlite3", ":memory:")
+		s.Require().NoError(err)
+
+		db.SetMaxOpenConns(1)
+
+		// No snapshot is taken of a new database.
+		snapshots := [][]uint64{}
+		snapshot := func(ctx context.Context, tx *sql.Tx, internalVersion uint64, externalVersion uint64) error {
+			snapshots = append(snapshots, []uint64{internalVersion, externalVersion})
+			return t.snapshotErr
+		}
+
+		newSchema := schemaMgr.Schema()
+		newSchema.Snapshot(snapshot)
+		_, err = newSchema.Ensure(db)
+		s.Require().NoError(err)
+		s.Empty(snapshots)
+
+		schemaMgr.updates[updateInternal] = append(schemaMgr.updates[updateInternal], t.upgradesInternal...)
+		schemaMgr.updates[updateExternal] = append(schemaMgr.updates[updateExternal], t.upgradesExternal...)
+
+		newSchema = schemaMgr.Schema()
+		newSchema.Snapshot(snapshot)
+		_, err = newSchema.Ensure(db)
+		if t.snapshotErr != nil {
+			s.ErrorIs(err, t.snapshotErr)
+		} else {
+			s.NoError(err)
+		}
+
+		if t.expectSnapshot {
+			s.Equal([][]uint64{{2, 1}}, snapshots)
+		} else {
+			s.Empty(snapshots)
+		}
+
+		ctx := context.Background()
+		tx, err := db.BeginTx(ctx, nil)
+		s.Require().NoError(err)
+
+		applied, pending, err := newSchema.Status(ctx, tx)
+		s.NoError(err)
+		s.NoError(tx.Rollback())
+
+		if t.snapshotErr != nil {
+			s.Len(applied, 3)
+			s.NotEmpty(pending)
+		} else {
+			s.Len(applied, len(schemaMgr.updates[updateInternal])+len(schemaMgr.updates[updateExternal]))
+			s.Empty(pending)
+		}
+
+		s.NoError(db.Close())
+	}
+}
+
 // NewTestDBWithSchema returns a sqlite DB set up with the given schema updates.
 func NewTestDBWithSchema(schemaManager *SchemaUpdateManager) (*sql.DB, error) {
 	db, err := sql.Open("sqlite3", ":memory:")
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"os"
+
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/internal/db/update"
+	"github.com/canonical/microcluster/v3/internal/sys"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// SetUpgradeRequiredHook sets the function to run once this cluster member finds out it is behind the rest of the cluster.
+func (db *DqliteDB) SetUpgradeRequiredHook(hook func(ctx context.Context) error) {
+	db.upgradeLock.Lock()
+	defer db.upgradeLock.Unlock()
+
+	db.upgradeRequiredHook = hook
+}
+
+// UpgradeStatus returns the upgrade progress of this cluster member.
+// The status is empty unless the cluster member has found out it is behind the rest of the cluster.
+func (db *DqliteDB) UpgradeStatus() types.MemberStatus {
+	db.upgradeLock.Lock()
+	defer db.upgradeLock.Unlock()
+
+	return db.upgradeStatus
+}
+
+// RequireUpgrade records that this cluster member is behind the rest of the cluster and needs to be upgraded, and runs
+// the upgrade required hook and the SCHEMA_UPDATE executable in the background. The cluster member is considered to be
+// upgrading until it restarts with the new version once either of them started an upgrade. Otherwise it stays in need
+// of an upgrade, and the next call tries again.
+func (db *DqliteDB) RequireUpgrade(reason string) {
+	db.upgradeLock.Lock()
+	if db.upgradeStatus == types.MemberUpgrading || db.upgradeRunning {
+		db.upgradeLock.Unlock()
+		return
+	}
+
+	first := db.upgradeStatus == ""
+	db.upgradeStatus = types.MemberNeedsUpgrade
+	db.upgradeRunning = true
+	hook := db.upgradeRequiredHook
+	db.upgradeLock.Unlock()
+
+	if first {
+		logger.Warn("NEEDS UPGRADE: This cluster member is behind the rest of the cluster", logger.Ctx{"address": db.listenAddr.String(), "reason": reason})
+	}
+
+	go func() {
+		if first {
+			db.recordUpgradeStatus(types.MemberNeedsUpgrade)
+		}
+
+		started := false
+		if hook != nil {
+			err := hook(db.ctx)
+			if err != nil {
+				logger.Warn("Failed to run OnUpgradeRequired hook", logger.Ctx{"error": err})
+			} else {
+				started = true
+			}
+		}
+
+		if os.Getenv(sys.SchemaUpdate) != "" {
+			err := db.Update()
+			if err != nil {
+				logger.Warn("Failed to trigger cluster member update", logger.Ctx{"error": err})
+			} else {
+				started = true
+			}
+		}
+
+		db.upgradeLock.Lock()
+		db.upgradeRunning = false
+		if started {
+			db.upgradeStatus = types.MemberUpgrading
+		}
+
+		db.upgradeLock.Unlock()
+
+		if !started {
+			logger.Debug("No upgrade was started, retrying once the cluster member is found to be behind again")
+			return
+		}
+
+		db.recordUpgradeStatus(types.MemberUpgrading)
+	}()
+}
+
+// recordUpgradeStatus records the given upgrade status of this cluster member in the database, so that the progress of
+// an upgrade can be followed from any cluster member. Failures are only logged, as the database may not be usable if
+// this cluster member is too far behind.
+func (db *DqliteDB) recordUpgradeStatus(status types.MemberStatus) {
+	err := db.Transaction(db.ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return update.UpdateClusterMemberUpgradeStatus(ctx, tx, string(status), db.memberName())
+	})
+	if err != nil {
+		logger.Warn("Failed to record upgrade status", logger.Ctx{"status": status, "error": err})
+	}
+}
+
+// SchemaStatus returns the schema updates applied to the database, those of this cluster member that are pending, and
+// the schema versions supported by each cluster member. Only the status and local versions are available until the
+// database has been opened.
+func (db *DqliteDB) SchemaStatus(ctx context.Context) (*types.DatabaseSchema, error) {
+	schemaInternal, schemaExternal, _ := db.schema.Version()
+	status := &types.DatabaseSchema{
+		Status:                db.Status(),
+		SchemaInternalVersion: schemaInternal,
+		SchemaExternalVersion: schemaExternal,
+		Applied:               []types.DatabaseSchemaUpdate{},
+		Pending:               []types.DatabaseSchemaUpdate{},
+		Members:               []types.DatabaseSchemaMember{},
+	}
+
+	if status.Status != types.DatabaseReady && status.Status != types.DatabaseWaiting {
+		return status, nil
+	}
+
+	err := db.ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		status.Applied, status.Pending, err = db.schema.Status(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		status.Members, err = update.GetClusterMemberSchemas(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// SchemaDryRun applies the schema updates of this cluster member that are pending in a transaction which is then
+// rolled back, and returns the updates that were applied. This is useful to check that the updates succeed against the
+// current content of the database while this cluster member waits for the rest of the cluster to be upgraded.
+func (db *DqliteDB) SchemaDryRun(ctx context.Context) ([]types.DatabaseSchemaUpdate, error) {
+	status := db.Status()
+	if status != types.DatabaseWaiting && status != types.DatabaseReady {
+		return nil, api.StatusErrorf(http.StatusServiceUnavailable, "Database is not ready yet: %v", status)
+	}
+
+	var updates []types.DatabaseSchemaUpdate
+	err := db.retry(ctx, func(ctx context.Context) error {
+		var err error
+		updates, err = db.schema.DryRun(ctx, db.db)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return updates, nil
+}
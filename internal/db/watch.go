@@ -0,0 +1,291 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// DefaultChangeLogRetention is how long changes are kept in the change log before the leader removes them.
+const DefaultChangeLogRetention = time.Hour
+
+// changeBatchSize is the maximum number of changes read from the change log at once.
+const changeBatchSize = 1000
+
+// watcherBufferSize is the number of changes buffered for a watcher before it is considered to not keep up.
+const watcherBufferSize = 64
+
+// watcher is a subscription to the changes made to a set of tables.
+type watcher struct {
+	ctx     context.Context
+	tables  map[string]bool
+	afterID int64 // ID of the last change made before the watcher was registered.
+	ch      chan types.DatabaseChange
+}
+
+// SetChangeHook sets the function to run after this cluster member commits a transaction which changed a watched
+// table, in order to notify the other cluster members.
+func (db *DqliteDB) SetChangeHook(hook func(ctx context.Context) error) {
+	db.changeLock.Lock()
+	defer db.changeLock.Unlock()
+
+	db.changeHook = hook
+}
+
+// NotifyChanges informs this cluster member that a transaction changing a watched table may have been committed,
+// so that the changes are delivered to its watchers.
+func (db *DqliteDB) NotifyChanges() {
+	select {
+	case db.watchCh <- struct{}{}:
+	default:
+	}
+}
+
+// Watch returns a channel receiving the insert, update, and delete events of the given tables, as made by any cluster
+// member after the call to Watch. Events are delivered after each committed transaction, in the order of the change
+// log. The channel is closed once the given context is cancelled. It is also closed if the watcher does not keep up
+// and its buffer of events fills, so that it does not hold up the delivery of events to other watchers.
+//
+// Only the tables registered with the WatchedTables daemon argument can be watched. Their changes are recorded by
+// triggers created when schema updates are applied. Tables must have a rowid.
+func (db *DqliteDB) Watch(ctx context.Context, tables ...string) (<-chan types.DatabaseChange, error) {
+	if len(tables) == 0 {
+		return nil, fmt.Errorf("No tables to watch")
+	}
+
+	err := db.IsOpen(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher{
+		ctx:    ctx,
+		tables: make(map[string]bool, len(tables)),
+		ch:     make(chan types.DatabaseChange, watcherBufferSize),
+	}
+
+	for _, table := range tables {
+		name, ok := db.watchedTable(table)
+		if !ok {
+			return nil, api.StatusErrorf(http.StatusBadRequest, "Table %q is not watched for changes", table)
+		}
+
+		w.tables[name] = true
+	}
+
+	err = db.ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM core_change_log").Scan(&w.afterID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db.watchLock.Lock()
+	if db.watchers == nil {
+		db.watchers = map[*watcher]bool{}
+	}
+
+	db.watchers[w] = true
+	if !db.watchRunning {
+		db.watchRunning = true
+		db.watchLastID = w.afterID
+		go db.dispatchChanges()
+	}
+
+	db.watchLock.Unlock()
+
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-db.ctx.Done():
+		}
+
+		db.watchLock.Lock()
+		db.removeWatcher(w)
+		db.watchLock.Unlock()
+	}()
+
+	return w.ch, nil
+}
+
+// watchedTable returns the name of the given table as registered to be watched for changes, if it is.
+func (db *DqliteDB) watchedTable(table string) (string, bool) {
+	for _, name := range db.schema.WatchedTables() {
+		if strings.EqualFold(name, table) {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// removeWatcher stops delivering changes to the given watcher and closes its channel, unless it was already removed.
+// The watchLock must be held.
+func (db *DqliteDB) removeWatcher(w *watcher) {
+	if !db.watchers[w] {
+		return
+	}
+
+	delete(db.watchers, w)
+	close(w.ch)
+}
+
+// dispatchChanges delivers the changes recorded in the change log to the watchers of this cluster member, whenever it
+// is notified of a committed transaction, and at least once every heartbeat interval in case a notification was missed.
+// It stops once there are no watchers left.
+func (db *DqliteDB) dispatchChanges() {
+	interval := db.heartbeatInterval
+	if interval <= 0 {
+		interval = DefaultHeartbeatInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-db.ctx.Done():
+			return
+		case <-db.watchCh:
+		case <-ticker.C:
+		}
+
+		db.watchLock.Lock()
+		if len(db.watchers) == 0 {
+			db.watchRunning = false
+			db.watchLock.Unlock()
+			return
+		}
+
+		lastID := db.watchLastID
+		db.watchLock.Unlock()
+
+		// Only read the change log, so that delivering changes never notifies the other cluster members in turn.
+		var changes []types.DatabaseChange
+		err := db.ReadTransaction(db.ctx, func(ctx context.Context, tx *sql.Tx) error {
+			var err error
+			changes, err = getChanges(ctx, tx, lastID, changeBatchSize)
+
+			return err
+		})
+		if err != nil {
+			logger.Warn("Failed to read database change log", logger.Ctx{"error": err})
+			continue
+		}
+
+		db.watchLock.Lock()
+		for _, change := range changes {
+			for w := range db.watchers {
+				if change.ID <= w.afterID || !w.tables[change.Table] {
+					continue
+				}
+
+				// Never block on a watcher, as it would hold up all other watchers along with the watchLock.
+				select {
+				case w.ch <- change:
+				default:
+					logger.Warn("Dropping database change watcher that is not keeping up", logger.Ctx{"table": change.Table})
+					db.removeWatcher(w)
+				}
+			}
+
+			db.watchLastID = change.ID
+		}
+
+		db.watchLock.Unlock()
+
+		// Keep going if there may be more changes left to read.
+		if len(changes) == changeBatchSize {
+			db.NotifyChanges()
+		}
+	}
+}
+
+// changesCommitted notifies the watchers of this cluster member and, in the background, the other cluster members that
+// this cluster member committed a transaction which changed a watched table. Commits made while the other cluster
+// members are being notified are coalesced into a single notification sent once the current one completes.
+func (db *DqliteDB) changesCommitted() {
+	db.NotifyChanges()
+
+	db.changeHookQueued.Store(true)
+	if !db.changeHookRunning.CompareAndSwap(false, true) {
+		return
+	}
+
+	go func() {
+		for {
+			for db.changeHookQueued.Swap(false) {
+				db.changeLock.Lock()
+				hook := db.changeHook
+				db.changeLock.Unlock()
+
+				if hook == nil {
+					continue
+				}
+
+				err := hook(db.ctx)
+				if err != nil {
+					logger.Warn("Failed to notify cluster members of database changes", logger.Ctx{"error": err})
+				}
+			}
+
+			db.changeHookRunning.Store(false)
+
+			// Pick up commits queued after the last notification but before it was marked complete.
+			if !db.changeHookQueued.Load() || !db.changeHookRunning.CompareAndSwap(false, true) {
+				return
+			}
+		}
+	}()
+}
+
+// PruneChangeLog removes the changes older than the given age from the change log.
+func (db *DqliteDB) PruneChangeLog(ctx context.Context, maxAge time.Duration) error {
+	return db.Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, "DELETE FROM core_change_log WHERE created_at < ?", time.Now().UTC().Add(-maxAge).Format(time.DateTime))
+
+		return err
+	})
+}
+
+// getChangeSequence returns the ID of the last change recorded in the change log, including removed changes.
+func getChangeSequence(ctx context.Context, tx *sql.Tx) (int64, error) {
+	var seq int64
+	err := tx.QueryRowContext(ctx, "SELECT COALESCE((SELECT seq FROM sqlite_sequence WHERE name = 'core_change_log'), 0)").Scan(&seq)
+	if err != nil {
+		return 0, fmt.Errorf("Failed to get database change log sequence: %w", err)
+	}
+
+	return seq, nil
+}
+
+// getChanges returns up to limit changes from the change log with an ID greater than the given one, in order.
+func getChanges(ctx context.Context, tx *sql.Tx, afterID int64, limit int) ([]types.DatabaseChange, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT id, table_name, operation, row_id, created_at FROM core_change_log WHERE id > ? ORDER BY id LIMIT ?", afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get database changes: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	changes := []types.DatabaseChange{}
+	for rows.Next() {
+		change := types.DatabaseChange{}
+		err := rows.Scan(&change.ID, &change.Table, &change.Operation, &change.RowID, &change.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to scan database change: %w", err)
+		}
+
+		changes = append(changes, change)
+	}
+
+	return changes, rows.Err()
+}
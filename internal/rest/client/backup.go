@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/canonical/lxd/shared/logger"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/response"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetDatabaseBackup streams a consistent backup of the database as a gzip compressed tarball to the given writer.
+func (c *Client) GetDatabaseBackup(ctx context.Context, w io.Writer) error {
+	// The response body is streamed after the request returns, so the context must outlive it.
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	resp, err := c.QueryStructRaw(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("database", "backup"), nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			logger.Error("Failed to close database backup response body", logger.Ctx{"error": err})
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		_, err := response.ParseResponse(resp)
+		if err != nil {
+			return err
+		}
+
+		return fmt.Errorf("Failed to fetch database backup: %q", resp.Status)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	if err != nil {
+		return fmt.Errorf("Failed to read database backup: %w", err)
+	}
+
+	return nil
+}
+
+// GetDatabaseBackups returns the scheduled backups of the database stored on the cluster member.
+func (c *Client) GetDatabaseBackups(ctx context.Context) (*types.DatabaseBackups, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	backups := types.DatabaseBackups{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("database", "backups"), nil, &backups)
+
+	return &backups, err
+}
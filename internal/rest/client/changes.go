@@ -0,0 +1,30 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+	"github.com/gorilla/websocket"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+)
+
+// WatchDatabaseChanges returns a websocket connection over which the changes made to the given tables are received
+// as JSON encoded types.DatabaseChange events, until the connection is closed.
+func (c *Client) WatchDatabaseChanges(ctx context.Context, tables ...string) (*websocket.Conn, error) {
+	endpoint := api.NewURL().Path("database", "changes")
+	for _, table := range tables {
+		endpoint.WithQuery("table", table)
+	}
+
+	return c.RawWebsocket(ctx, internalTypes.PublicEndpoint, endpoint)
+}
+
+// NotifyDatabaseChanges informs the cluster member targeted by this client that changes were committed to watched tables.
+func NotifyDatabaseChanges(ctx context.Context, c *Client) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("database", "changes"), nil, nil)
+}
@@ -61,6 +61,53 @@ func (c *Client) DeleteClusterMember(ctx context.Context, name string, force boo
 	return c.QueryStruct(queryCtx, "DELETE", internalTypes.PublicEndpoint, endpoint, nil, nil)
 }
 
+// GetClusterMemberHealth returns the heartbeat health metrics of the cluster member with the given name.
+func (c *Client) GetClusterMemberHealth(ctx context.Context, name string) (*types.ClusterMemberHealth, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	health := types.ClusterMemberHealth{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("cluster", name, "health"), nil, &health)
+
+	return &health, err
+}
+
+// UpdateClusterMember applies the given changes to the cluster member with the given name.
+func (c *Client) UpdateClusterMember(ctx context.Context, name string, args types.ClusterMemberPatch) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "PATCH", internalTypes.PublicEndpoint, api.NewURL().Path("cluster", name), args, nil)
+}
+
+// UpdateClusterMemberRole assigns the given dqlite role to the cluster member with the given name.
+// Roles that dqlite's automatic roles adjustment would immediately undo are refused.
+func (c *Client) UpdateClusterMemberRole(ctx context.Context, name string, role string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	endpoint := api.NewURL().Path("cluster", name, "role")
+	return c.QueryStruct(queryCtx, "PUT", internalTypes.PublicEndpoint, endpoint, types.ClusterMemberRole{Role: role}, nil)
+}
+
+// RenameClusterMember renames the cluster member with the given name.
+func (c *Client) RenameClusterMember(ctx context.Context, name string, newName string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.PublicEndpoint, api.NewURL().Path("cluster", name), types.ClusterMemberRename{Name: newName}, nil)
+}
+
+// UpdateClusterMemberAddress moves the cluster member with the given name to a new address.
+// The cluster member switches to the new address once it has responded.
+func (c *Client) UpdateClusterMemberAddress(ctx context.Context, name string, address types.AddrPort) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	endpoint := api.NewURL().Path("cluster", name, "address")
+	return c.QueryStruct(queryCtx, "PUT", internalTypes.PublicEndpoint, endpoint, types.ClusterMemberAddress{Address: address}, nil)
+}
+
 // UpdateCertificate sets a new keypair and CA.
 func (c *Client) UpdateCertificate(ctx context.Context, name types.CertificateName, args types.KeyPair) error {
 	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetClusterConfig returns the cluster-wide configuration.
+func (c *Client) GetClusterConfig(ctx context.Context) (types.ClusterConfig, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	config := types.ClusterConfig{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("config"), nil, &config)
+
+	return config, err
+}
+
+// UpdateClusterConfig applies the given changes to the cluster-wide configuration.
+// Keys with an empty value are removed from the configuration.
+func (c *Client) UpdateClusterConfig(ctx context.Context, config types.ClusterConfig) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "PATCH", internalTypes.PublicEndpoint, api.NewURL().Path("config"), config, nil)
+}
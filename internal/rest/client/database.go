@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetDatabaseInfo returns diagnostic information about the database, as seen from the cluster member targeted by this client.
+func (c *Client) GetDatabaseInfo(ctx context.Context) (*types.DatabaseInfo, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	info := &types.DatabaseInfo{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("database"), nil, info)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// GetDatabaseSchema returns the applied and pending schema updates, as seen from the cluster member targeted by this client.
+func (c *Client) GetDatabaseSchema(ctx context.Context) (*types.DatabaseSchema, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	schema := &types.DatabaseSchema{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("database", "schema"), nil, schema)
+	if err != nil {
+		return nil, err
+	}
+
+	return schema, nil
+}
+
+// DryRunDatabaseSchema applies the pending schema updates of the cluster member targeted by this client without
+// committing them, and returns the updates that were applied.
+func (c *Client) DryRunDatabaseSchema(ctx context.Context) ([]types.DatabaseSchemaUpdate, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	updates := []types.DatabaseSchemaUpdate{}
+	err := c.QueryStruct(queryCtx, "POST", internalTypes.PublicEndpoint, api.NewURL().Path("database", "schema", "dry-run"), nil, &updates)
+	if err != nil {
+		return nil, err
+	}
+
+	return updates, nil
+}
@@ -41,3 +41,43 @@ func RunOnDaemonConfigUpdateHook(ctx context.Context, c *Client, config *types.D
 
 	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("hooks", string(internalTypes.OnDaemonConfigUpdate)), config, nil)
 }
+
+// RunOnClusterConfigUpdateHook executes the OnClusterConfigUpdate hook with the given configuration on the cluster member targeted by this client.
+func RunOnClusterConfigUpdateHook(ctx context.Context, c *Client, config types.ClusterConfig) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("hooks", string(internalTypes.OnClusterConfigUpdate)), config, nil)
+}
+
+// RunOnMemberRenameHook executes the OnMemberRename hook on the cluster member targeted by this client.
+func RunOnMemberRenameHook(ctx context.Context, c *Client, config internalTypes.HookRenameMemberOptions) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("hooks", string(internalTypes.OnMemberRename)), config, nil)
+}
+
+// RunOnMemberDownHook executes the OnMemberDown hook on the cluster member targeted by this client.
+func RunOnMemberDownHook(ctx context.Context, c *Client, config internalTypes.HookMemberStatusOptions) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("hooks", string(internalTypes.OnMemberDown)), config, nil)
+}
+
+// RunOnMemberUpHook executes the OnMemberUp hook on the cluster member targeted by this client.
+func RunOnMemberUpHook(ctx context.Context, c *Client, config internalTypes.HookMemberStatusOptions) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("hooks", string(internalTypes.OnMemberUp)), config, nil)
+}
+
+// RunOnMemberEvictedHook executes the OnMemberEvicted hook on the cluster member targeted by this client.
+func RunOnMemberEvictedHook(ctx context.Context, c *Client, config internalTypes.HookMemberStatusOptions) error {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	return c.QueryStruct(queryCtx, "POST", internalTypes.InternalEndpoint, api.NewURL().Path("hooks", string(internalTypes.OnMemberEvicted)), config, nil)
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/canonical/lxd/shared/api"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+// GetUpgradeStatus returns the upgrade progress of each cluster member.
+func (c *Client) GetUpgradeStatus(ctx context.Context) (*types.ClusterUpgrade, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	upgrade := types.ClusterUpgrade{}
+	err := c.QueryStruct(queryCtx, "GET", internalTypes.PublicEndpoint, api.NewURL().Path("upgrade"), nil, &upgrade)
+
+	return &upgrade, err
+}
+
+// WaitUpgrade blocks until all cluster members have converged on the same schema version and API extensions, or the
+// context is cancelled. Errors are retried, as cluster members are expected to restart during an upgrade.
+func (c *Client) WaitUpgrade(ctx context.Context) error {
+	var errLast error
+	for {
+		upgrade, err := c.GetUpgradeStatus(ctx)
+		if err == nil && upgrade.Converged {
+			return nil
+		}
+
+		if err != nil {
+			errLast = err
+		} else {
+			blocking := []string{}
+			for _, member := range upgrade.Members {
+				if member.Blocking {
+					blocking = append(blocking, member.Name)
+				}
+			}
+
+			errLast = fmt.Errorf("Cluster members %v have not yet been upgraded", blocking)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Cluster did not converge before context deadline exceeded: %w", errLast)
+		case <-time.After(time.Second):
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package resources
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/v3/internal/db"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var databaseBackupCmd = rest.Endpoint{
+	Path: "database/backup",
+
+	Get: rest.EndpointAction{Handler: databaseBackupGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var databaseBackupsCmd = rest.Endpoint{
+	Path: "database/backups",
+
+	Get: rest.EndpointAction{Handler: databaseBackupsGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+// databaseBackupGet streams a consistent backup of the live database as a gzip compressed tarball.
+func databaseBackupGet(s state.State, r *http.Request) response.Response {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Take the backup before writing any of the response, so that errors can still be reported normally.
+	backup, err := intState.InternalDatabase.Backup(r.Context(), s.Version())
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to back up database: %w", err))
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		w.Header().Set("Content-Type", "application/gzip")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", db.BackupFileName(backup.Metadata.CreatedAt)))
+		w.WriteHeader(http.StatusOK)
+
+		return backup.WriteArchive(w)
+	})
+}
+
+// databaseBackupsGet lists the scheduled backups of the database stored on this cluster member.
+// Scheduled backups are stored on the cluster member that was the dqlite leader when taking them, and are not collected
+// in one place, so use the target parameter to inspect other cluster members.
+func databaseBackupsGet(s state.State, r *http.Request) response.Response {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	backups, err := db.ListBackups(s.FileSystem().BackupsDir)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	lastAttempt, lastError := intState.InternalDatabase.BackupStatus()
+
+	return response.SyncResponse(true, types.DatabaseBackups{LastAttempt: lastAttempt, LastError: lastError, Backups: backups})
+}
@@ -0,0 +1,93 @@
+package resources
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/ws"
+	"github.com/gorilla/websocket"
+
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var databaseChangesCmd = rest.Endpoint{
+	Path: "database/changes",
+
+	Get: rest.EndpointAction{Handler: databaseChangesGet, AccessHandler: access.AllowAuthenticated},
+}
+
+var databaseChangesInternalCmd = rest.Endpoint{
+	Path: "database/changes",
+
+	Post: rest.EndpointAction{Handler: databaseChangesInternalPost, AccessHandler: access.AllowAuthenticated},
+}
+
+// databaseChangesGet upgrades the connection to a websocket, over which the changes made to the tables given by the
+// table query parameters are sent as JSON encoded events until the connection is closed.
+func databaseChangesGet(s state.State, r *http.Request) response.Response {
+	tables := r.URL.Query()["table"]
+	if len(tables) == 0 {
+		return response.BadRequest(fmt.Errorf("At least one table to watch is required"))
+	}
+
+	ctx, cancel := context.WithCancel(r.Context())
+
+	changes, err := s.Database().Watch(ctx, tables...)
+	if err != nil {
+		cancel()
+
+		return response.SmartError(err)
+	}
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		defer cancel()
+
+		conn, err := ws.Upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = conn.Close() }()
+
+		// Stop watching once the client closes the connection. Nothing is expected to be read from it.
+		go func() {
+			defer cancel()
+
+			for {
+				_, _, err := conn.NextReader()
+				if err != nil {
+					return
+				}
+			}
+		}()
+
+		for change := range changes {
+			err := conn.WriteJSON(change)
+			if err != nil {
+				logger.Debug("Failed to send database change", logger.Ctx{"error": err})
+				return nil
+			}
+		}
+
+		return conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+	})
+}
+
+// databaseChangesInternalPost is called by other cluster members after they commit changes to watched tables,
+// so that they are delivered to the watchers of this cluster member.
+func databaseChangesInternalPost(s state.State, r *http.Request) response.Response {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	intState.InternalDatabase.NotifyChanges()
+
+	return response.EmptySyncResponse
+}
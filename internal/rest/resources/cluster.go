@@ -15,16 +15,19 @@ import (
 	"sync"
 	"time"
 
+	dqlite "github.com/canonical/go-dqlite/app"
 	dqliteClient "github.com/canonical/go-dqlite/client"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared"
 	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
+	"github.com/canonical/lxd/shared/revert"
 	"github.com/gorilla/mux"
 	"golang.org/x/sys/unix"
 
 	"github.com/canonical/microcluster/v3/client"
 	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/db"
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
@@ -53,9 +56,29 @@ var clusterInternalCmd = rest.Endpoint{
 var clusterMemberCmd = rest.Endpoint{
 	Path: "cluster/{name}",
 
+	Post:   rest.EndpointAction{Handler: clusterMemberPost, AccessHandler: access.AllowAuthenticated},
+	Patch:  rest.EndpointAction{Handler: clusterMemberPatch, AccessHandler: access.AllowAuthenticated},
 	Delete: rest.EndpointAction{Handler: clusterMemberDelete, AccessHandler: access.AllowAuthenticated},
 }
 
+var clusterMemberRoleCmd = rest.Endpoint{
+	Path: "cluster/{name}/role",
+
+	Put: rest.EndpointAction{Handler: clusterMemberRolePut, AccessHandler: access.AllowAuthenticated},
+}
+
+var clusterMemberAddressCmd = rest.Endpoint{
+	Path: "cluster/{name}/address",
+
+	Put: rest.EndpointAction{Handler: clusterMemberAddressPut, AccessHandler: access.AllowAuthenticated},
+}
+
+var clusterMemberHealthCmd = rest.Endpoint{
+	Path: "cluster/{name}/health",
+
+	Get: rest.EndpointAction{Handler: clusterMemberHealthGet, AccessHandler: access.AllowAuthenticated},
+}
+
 var clusterMemberInternalCmd = rest.Endpoint{
 	Path: "cluster/{name}",
 
@@ -128,14 +151,17 @@ func clusterPost(s state.State, r *http.Request) response.Response {
 
 	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
 		dbClusterMember := cluster.CoreClusterMember{
-			Name:           req.Name,
-			Address:        req.Address.String(),
-			Certificate:    req.Certificate.String(),
-			SchemaInternal: req.SchemaInternalVersion,
-			SchemaExternal: req.SchemaExternalVersion,
-			APIExtensions:  req.Extensions,
-			Heartbeat:      time.Time{},
-			Role:           cluster.Pending,
+			Name:            req.Name,
+			Address:         req.Address.String(),
+			Certificate:     req.Certificate.String(),
+			SchemaInternal:  req.SchemaInternalVersion,
+			SchemaExternal:  req.SchemaExternalVersion,
+			APIExtensions:   req.Extensions,
+			Heartbeat:       time.Time{},
+			Role:            cluster.Pending,
+			FailureDomain:   req.FailureDomain,
+			HeartbeatStatus: types.MemberOnline,
+			Version:         req.Version,
 		}
 
 		record, err := cluster.GetCoreTokenRecord(ctx, tx, req.Secret)
@@ -246,8 +272,14 @@ func clusterGet(s state.State, r *http.Request) response.Response {
 		return response.SmartError(api.StatusErrorf(http.StatusServiceUnavailable, "%s", string(status)))
 	}
 
+	// While waiting for an upgrade, listing the cluster members may need to prepare the schema of an older version.
+	transaction := s.Database().ReadTransaction
+	if status != types.DatabaseReady {
+		transaction = s.Database().Transaction
+	}
+
 	var apiClusterMembers []types.ClusterMember
-	err := s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+	err := transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
 		var err error
 		var clusterMembers []cluster.CoreClusterMember
 		var awaitingUpgrade map[string]bool
@@ -675,3 +707,897 @@ func clusterMemberDelete(s state.State, r *http.Request) response.Response {
 
 	return response.EmptySyncResponse
 }
+
+// clusterMemberPatch updates the labels of a cluster member. Labels with an empty value are removed.
+func clusterMemberPatch(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := types.ClusterMemberPatch{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	for key, value := range req.Labels {
+		err := types.ValidateLabel(key, value)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+	}
+
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		clusterMember, err := cluster.GetCoreClusterMember(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		if clusterMember.Labels == nil {
+			clusterMember.Labels = types.Labels{}
+		}
+
+		for key, value := range req.Labels {
+			if value == "" {
+				delete(clusterMember.Labels, key)
+			} else {
+				clusterMember.Labels[key] = value
+			}
+		}
+
+		return cluster.UpdateCoreClusterMember(ctx, tx, name, *clusterMember)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// clusterMemberPost renames a cluster member. The request is handled by the member being renamed, as it has to
+// replace its own server certificate with one matching the new name.
+func clusterMemberPost(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := types.ClusterMemberRename{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	err = utils.ValidateFQDN(req.Name)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Cluster member name %q is not a valid FQDN: %w", req.Name, err))
+	}
+
+	if req.Name == name {
+		return response.BadRequest(fmt.Errorf("Cluster member is already named %q", name))
+	}
+
+	allRemotes := s.Remotes().RemotesByName()
+	remote, ok := allRemotes[name]
+	if !ok {
+		return response.NotFound(fmt.Errorf("No remote exists with the given name %q", name))
+	}
+
+	_, ok = allRemotes[req.Name]
+	if ok {
+		return response.Conflict(fmt.Errorf("A cluster member with name %q already exists", req.Name))
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	// Forward the request to the member being renamed.
+	if remote.Address.String() != s.Address().URL.Host {
+		publicKey, err := s.ClusterCert().PublicKeyX509()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		c, err := internalClient.New(remote.URL(), s.ServerCert(), publicKey, false)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = c.RenameClusterMember(ctx, name, req.Name)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// The truststore may not have caught up with a member that just joined, so also check the database.
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		exists, err := cluster.CoreClusterMemberExists(ctx, tx, req.Name)
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			return api.StatusErrorf(http.StatusConflict, "A cluster member with name %q already exists", req.Name)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	certPEM, keyPEM, err := shared.GenerateMemCert(false, shared.CertOptions{AddHosts: true, CommonName: req.Name})
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to generate server certificate for %q: %w", req.Name, err))
+	}
+
+	newCert, err := types.ParseX509Certificate(string(certPEM))
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	localClient, err := internalClient.New(s.FileSystem().ControlSocket(), nil, nil, false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	// Trust the new name and certificate on all cluster members before we start using them.
+	newMember := types.ClusterMemberLocal{Name: req.Name, Address: remote.Address, Certificate: *newCert}
+	err = internalClient.AddTrustStoreEntry(ctx, localClient, newMember)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to add truststore entry for %q: %w", req.Name, err))
+	}
+
+	reverter.Add(func() {
+		err := internalClient.DeleteTrustStoreEntry(context.Background(), localClient, req.Name)
+		if err != nil {
+			logger.Error("Failed to remove truststore entry after failed rename", logger.Ctx{"name": req.Name, "error": err})
+		}
+	})
+
+	oldCert := s.ServerCert()
+	err = intState.ReplaceServerCert(certPEM, keyPEM)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to replace server certificate: %w", err))
+	}
+
+	reverter.Add(func() {
+		err := intState.ReplaceServerCert(oldCert.PublicKey(), oldCert.PrivateKey())
+		if err != nil {
+			logger.Error("Failed to restore server certificate after failed rename", logger.Ctx{"error": err})
+		}
+	})
+
+	err = intState.SetConfig(trust.Location{Name: req.Name, Address: remote.Address})
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to update daemon config: %w", err))
+	}
+
+	reverter.Add(func() {
+		err := intState.SetConfig(remote.Location)
+		if err != nil {
+			logger.Error("Failed to restore daemon config after failed rename", logger.Ctx{"error": err})
+		}
+	})
+
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		clusterMember, err := cluster.GetCoreClusterMember(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		clusterMember.Name = req.Name
+		clusterMember.Certificate = newCert.String()
+
+		return cluster.UpdateCoreClusterMember(ctx, tx, name, *clusterMember)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	reverter.Success()
+
+	// Heartbeats reconcile the truststores with the database, so the old entry may already be gone.
+	_, ok = s.Remotes().RemotesByName()[name]
+	if ok {
+		err = internalClient.DeleteTrustStoreEntry(ctx, localClient, name)
+		if err != nil {
+			logger.Warn("Failed to remove truststore entry of renamed cluster member", logger.Ctx{"name": name, "error": err})
+		}
+	}
+
+	// Run the OnMemberRename hook locally.
+	err = intState.Hooks.OnMemberRename(ctx, s, name, req.Name)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to run hook on %q after rename: %w", s.Name(), err))
+	}
+
+	clusterClients, err := s.Cluster(false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Run the OnMemberRename hook on all other members.
+	remotes := s.Remotes()
+	err = clusterClients.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
+		c.SetClusterNotification()
+		addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+		if err != nil {
+			return err
+		}
+
+		remote := remotes.RemoteByAddress(addrPort)
+		if remote == nil {
+			return fmt.Errorf("No remote found at address %q to run the %q hook", c.URL().URL.Host, internalTypes.OnMemberRename)
+		}
+
+		return internalClient.RunOnMemberRenameHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookRenameMemberOptions{OldName: name, NewName: req.Name})
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// clusterMemberHealthGet returns the heartbeat health metrics of a cluster member, as last recorded by the leader.
+func clusterMemberHealthGet(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	var health types.ClusterMemberHealth
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		clusterMember, err := cluster.GetCoreClusterMember(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		apiClusterMember, err := clusterMember.ToAPI()
+		if err != nil {
+			return err
+		}
+
+		health = types.ClusterMemberHealth{
+			Name:              apiClusterMember.Name,
+			Status:            apiClusterMember.HeartbeatStatus,
+			LastHeartbeat:     apiClusterMember.LastHeartbeat,
+			Latency:           apiClusterMember.HeartbeatLatency,
+			ConsecutiveMisses: apiClusterMember.HeartbeatFailures,
+			LastError:         apiClusterMember.HeartbeatError,
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, health)
+}
+
+// clusterMemberRolePut assigns a new dqlite role to a cluster member.
+// Roles that dqlite's automatic roles adjustment or the failure domain rebalancing would immediately undo are refused.
+func clusterMemberRolePut(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := types.ClusterMemberRole{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	newRole, err := parseDqliteRole(req.Role)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	remote, ok := s.Remotes().RemotesByName()[name]
+	if !ok {
+		return response.NotFound(fmt.Errorf("No remote exists with the given name %q", name))
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second*30)
+	defer cancel()
+
+	leader, err := s.Database().Leader(ctx)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	leaderInfo, err := leader.Leader(ctx)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// If we are not the leader, just forward the request.
+	if leaderInfo.Address != s.Address().URL.Host {
+		client, err := s.Leader()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = client.UpdateClusterMemberRole(r.Context(), name, req.Role)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	info, err := leader.Cluster(ctx)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Send a small request to each dqlite member, including the target, to determine which ones are online.
+	reachable, err := reachableDqliteMembers(ctx, s, info, "")
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	node, err := validateRoleChange(info, leaderInfo.Address, remote.Address.String(), newRole, reachable)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Nothing to do if the member already has the requested role.
+	if node.Role == newRole {
+		return response.EmptySyncResponse
+	}
+
+	clusterMembers := map[string]types.ClusterMember{}
+	err = s.Database().ReadTransaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		dbClusterMembers, err := cluster.GetCoreClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, clusterMember := range dbClusterMembers {
+			if !reachable[clusterMember.Address] {
+				continue
+			}
+
+			apiClusterMember, err := clusterMember.ToAPI()
+			if err != nil {
+				return err
+			}
+
+			clusterMembers[clusterMember.Address] = *apiClusterMember
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = validateRoleStability(info, leaderInfo.ID, node.Address, newRole, reachable, clusterMembers)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = leader.Assign(ctx, node.ID, newRole)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to assign role %q to cluster member %q: %w", newRole.String(), name, err))
+	}
+
+	// Record the new role and collect the role status of all members for the OnHeartbeat hook.
+	roleStatusMap := map[string]types.RoleStatus{}
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		dbClusterMembers, err := cluster.GetCoreClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, clusterMember := range dbClusterMembers {
+			if clusterMember.Name != name {
+				roleStatusMap[clusterMember.Name] = types.RoleStatus{Old: string(clusterMember.Role), New: string(clusterMember.Role)}
+				continue
+			}
+
+			roleStatusMap[clusterMember.Name] = types.RoleStatus{Old: string(clusterMember.Role), New: newRole.String()}
+			clusterMember.Role = cluster.Role(newRole.String())
+			err = cluster.UpdateCoreClusterMember(ctx, tx, clusterMember.Name, clusterMember)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	hookCtx, hookCancel := context.WithCancel(r.Context())
+	err = intState.Hooks.OnHeartbeat(hookCtx, s, roleStatusMap)
+	hookCancel()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// clusterMemberAddressPut moves a cluster member to a new address without it leaving the cluster.
+// The request is handled by the member being moved, as it has to restart its own listener and dqlite. It does so after
+// responding, as restarting the listener would otherwise cut off the response. Failures past that point are logged and
+// the old address is restored.
+func clusterMemberAddressPut(s state.State, r *http.Request) response.Response {
+	name, err := url.PathUnescape(mux.Vars(r)["name"])
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	req := types.ClusterMemberAddress{}
+	err = json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	if !req.Address.IsValid() {
+		return response.BadRequest(fmt.Errorf("Invalid address %q", req.Address.String()))
+	}
+
+	allRemotes := s.Remotes().RemotesByName()
+	remote, ok := allRemotes[name]
+	if !ok {
+		return response.NotFound(fmt.Errorf("No remote exists with the given name %q", name))
+	}
+
+	for _, otherRemote := range allRemotes {
+		if otherRemote.Address == req.Address {
+			return response.Conflict(fmt.Errorf("Address %q is already in use by cluster member %q", req.Address.String(), otherRemote.Name))
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	// Forward the request to the member being moved.
+	if remote.Address.String() != s.Address().URL.Host {
+		publicKey, err := s.ClusterCert().PublicKeyX509()
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		c, err := internalClient.New(remote.URL(), s.ServerCert(), publicKey, false)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		err = c.UpdateClusterMemberAddress(ctx, name, req.Address)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		return response.EmptySyncResponse
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	leader, err := s.Database().Leader(ctx)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	leaderInfo, err := leader.Leader(ctx)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	info, err := leader.Cluster(ctx)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// With no other dqlite member to record the new address, this member rewrites its dqlite configuration locally
+	// instead of being removed and added again.
+	lone := len(info) == 1 && info[0].Address == remote.Address.String()
+	node := &dqliteClient.NodeInfo{}
+	if lone {
+		node = &info[0]
+	} else {
+		// Send a small request to each other dqlite member to determine which ones would keep quorum.
+		reachable, err := reachableDqliteMembers(ctx, s, info, remote.Address.String())
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		node, err = validateAddressChange(info, remote.Address.String(), reachable)
+		if err != nil {
+			return response.SmartError(err)
+		}
+
+		// The leader can't remove itself from the dqlite configuration, so hand over leadership first.
+		if leaderInfo.Address == node.Address {
+			for _, other := range info {
+				if other.Role != dqliteClient.Voter || !reachable[other.Address] {
+					continue
+				}
+
+				err = leader.Transfer(ctx, other.ID)
+				if err != nil {
+					return response.SmartError(fmt.Errorf("Failed to transfer dqlite leadership to %q: %w", other.Address, err))
+				}
+
+				break
+			}
+
+			leader, err = s.Database().Leader(ctx)
+			if err != nil {
+				return response.SmartError(err)
+			}
+		}
+	}
+
+	localClient, err := internalClient.New(s.FileSystem().ControlSocket(), nil, nil, false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	// Propagate the new address to all truststores while we are still reachable on the old address.
+	newMember := types.ClusterMemberLocal{Name: name, Address: req.Address, Certificate: remote.Certificate}
+	err = internalClient.AddTrustStoreEntry(ctx, localClient, newMember)
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to update truststore entry for %q: %w", name, err))
+	}
+
+	reverter.Add(func() {
+		oldMember := types.ClusterMemberLocal{Name: name, Address: remote.Address, Certificate: remote.Certificate}
+		err := internalClient.AddTrustStoreEntry(context.Background(), localClient, oldMember)
+		if err != nil {
+			logger.Error("Failed to restore truststore entry after failed address change", logger.Ctx{"name": name, "error": err})
+		}
+	})
+
+	if !lone {
+		// Replace the dqlite record of this member with one for the new address. It is re-added as a spare so
+		// that quorum is unaffected, and its original role is restored once dqlite is running on the new address.
+		err = leader.Remove(ctx, node.ID)
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed to remove dqlite record of %q: %w", node.Address, err))
+		}
+
+		reverter.Add(func() {
+			err := leader.Add(context.Background(), dqliteClient.NodeInfo{ID: node.ID, Address: node.Address, Role: dqliteClient.Spare})
+			if err != nil {
+				logger.Error("Failed to restore dqlite record after failed address change", logger.Ctx{"address": node.Address, "error": err})
+			}
+		})
+
+		err = leader.Add(ctx, dqliteClient.NodeInfo{ID: node.ID, Address: req.Address.String(), Role: dqliteClient.Spare})
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed to add dqlite record for %q: %w", req.Address.String(), err))
+		}
+
+		reverter.Add(func() {
+			err := leader.Remove(context.Background(), node.ID)
+			if err != nil {
+				logger.Error("Failed to remove dqlite record after failed address change", logger.Ctx{"address": req.Address.String(), "error": err})
+			}
+		})
+	}
+
+	// Moving to the new address stops the listener serving this request, so only do so once the response is sent.
+	moveReverter := reverter.Clone()
+	reverter.Success()
+
+	go func() {
+		<-r.Context().Done() // Wait until request is finished.
+
+		defer moveReverter.Fail()
+
+		err := moveClusterMember(intState, name, remote.Address, req.Address, *node, !lone)
+		if err != nil {
+			logger.Error("Failed to change cluster member address", logger.Ctx{"name": name, "address": req.Address.String(), "error": err})
+			return
+		}
+
+		moveReverter.Success()
+	}()
+
+	return response.ManualResponse(func(w http.ResponseWriter) error {
+		err := response.EmptySyncResponse.Render(w, r)
+		if err != nil {
+			return err
+		}
+
+		f, ok := w.(http.Flusher)
+		if !ok {
+			return fmt.Errorf("ResponseWriter is not type http.Flusher")
+		}
+
+		f.Flush()
+		return nil
+	})
+}
+
+// moveClusterMember moves this cluster member from the old address to the new one, records the new address in the
+// database, and restores the given original dqlite role of the member if requested.
+// An error is only returned if the address change itself failed, in which case the old address is restored.
+func moveClusterMember(s *internalState.InternalState, name string, oldAddress types.AddrPort, newAddress types.AddrPort, node dqliteClient.NodeInfo, restoreRole bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	reverter := revert.New()
+	defer reverter.Fail()
+
+	err := s.UpdateAddress(newAddress)
+	if err != nil {
+		return fmt.Errorf("Failed to move to address %q: %w", newAddress.String(), err)
+	}
+
+	reverter.Add(func() {
+		err := s.UpdateAddress(oldAddress)
+		if err != nil {
+			logger.Error("Failed to restore address after failed address change", logger.Ctx{"address": oldAddress.String(), "error": err})
+		}
+	})
+
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		clusterMember, err := cluster.GetCoreClusterMember(ctx, tx, name)
+		if err != nil {
+			return err
+		}
+
+		clusterMember.Address = newAddress.String()
+
+		return cluster.UpdateCoreClusterMember(ctx, tx, name, *clusterMember)
+	})
+	if err != nil {
+		return err
+	}
+
+	reverter.Success()
+
+	// Restore the original dqlite role, unless dqlite already promoted the member on startup.
+	if !restoreRole || node.Role == dqliteClient.Spare {
+		return nil
+	}
+
+	err = restoreDqliteRole(ctx, s, node)
+	if err != nil {
+		logger.Error("Cluster member address was changed, but its dqlite role could not be restored", logger.Ctx{"name": name, "address": newAddress.String(), "role": node.Role.String(), "error": err})
+	}
+
+	return nil
+}
+
+// restoreDqliteRole assigns the role of the given dqlite record to the dqlite member with the same ID, if it differs.
+func restoreDqliteRole(ctx context.Context, s state.State, node dqliteClient.NodeInfo) error {
+	leader, err := s.Database().Leader(ctx)
+	if err != nil {
+		return err
+	}
+
+	info, err := leader.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, newNode := range info {
+		if newNode.ID != node.ID || newNode.Role == node.Role {
+			continue
+		}
+
+		return leader.Assign(ctx, node.ID, node.Role)
+	}
+
+	return nil
+}
+
+// reachableDqliteMembers checks whether each dqlite member other than the one with the given address is ready,
+// and returns the result keyed by address. An empty address checks all dqlite members.
+func reachableDqliteMembers(ctx context.Context, s state.State, nodes []dqliteClient.NodeInfo, address string) (map[string]bool, error) {
+	clusterCert, err := s.ClusterCert().PublicKeyX509()
+	if err != nil {
+		return nil, err
+	}
+
+	reachable := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		if node.Address == address {
+			continue
+		}
+
+		addr := api.NewURL().Scheme("https").Host(node.Address)
+		d, err := internalClient.New(*addr, s.ServerCert(), clusterCert, false)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to create HTTPS client for cluster member with address %q: %w", addr.String(), err)
+		}
+
+		reachable[node.Address] = d.CheckReady(ctx) == nil
+	}
+
+	return reachable, nil
+}
+
+// validateAddressChange checks that temporarily removing the dqlite member with the given address does not
+// jeopardize quorum, and returns the current dqlite record of that member.
+// The reachable map holds the reachability of all other dqlite members by address.
+func validateAddressChange(nodes []dqliteClient.NodeInfo, address string, reachable map[string]bool) (*dqliteClient.NodeInfo, error) {
+	target, err := findDqliteMember(nodes, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only removing a voter can affect quorum.
+	if target.Role != dqliteClient.Voter {
+		return target, nil
+	}
+
+	err = validateVoterRemoval(nodes, address, reachable, "change the address of")
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// findDqliteMember returns the dqlite record of the member with the given address.
+func findDqliteMember(nodes []dqliteClient.NodeInfo, address string) (*dqliteClient.NodeInfo, error) {
+	for i, node := range nodes {
+		if node.Address == address {
+			return &nodes[i], nil
+		}
+	}
+
+	return nil, api.StatusErrorf(http.StatusNotFound, "No dqlite record exists for cluster member with address %q", address)
+}
+
+// validateVoterRemoval checks that a majority of the voters other than the one with the given address is reachable,
+// so that the cluster keeps quorum once that voter stops voting.
+// The action describes the operation for error messages, e.g. "demote".
+func validateVoterRemoval(nodes []dqliteClient.NodeInfo, address string, reachable map[string]bool, action string) error {
+	voters := 0
+	reachableVoters := 0
+	for _, node := range nodes {
+		if node.Address == address || node.Role != dqliteClient.Voter {
+			continue
+		}
+
+		voters++
+		if reachable[node.Address] {
+			reachableVoters++
+		}
+	}
+
+	if voters < 1 {
+		return api.StatusErrorf(http.StatusBadRequest, "Cannot %s the last remaining voter", action)
+	}
+
+	if reachableVoters <= voters/2 {
+		return api.StatusErrorf(http.StatusServiceUnavailable, "Cannot %s the voter as it would break quorum, only %d of %d remaining voters are reachable", action, reachableVoters, voters)
+	}
+
+	return nil
+}
+
+// parseDqliteRole returns the dqlite node role corresponding to the given string.
+func parseDqliteRole(role string) (dqliteClient.NodeRole, error) {
+	for _, nodeRole := range []dqliteClient.NodeRole{dqliteClient.Voter, dqliteClient.StandBy, dqliteClient.Spare} {
+		if nodeRole.String() == role {
+			return nodeRole, nil
+		}
+	}
+
+	return -1, fmt.Errorf("Invalid dqlite role %q", role)
+}
+
+// validateRoleChange checks that assigning the given role to the dqlite member with the given address does not
+// jeopardize quorum, and returns the current dqlite record of that member.
+// The reachable map holds the reachability of all other dqlite members by address.
+func validateRoleChange(nodes []dqliteClient.NodeInfo, leaderAddress string, address string, newRole dqliteClient.NodeRole, reachable map[string]bool) (*dqliteClient.NodeInfo, error) {
+	target, err := findDqliteMember(nodes, address)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only demotions of voters can affect quorum.
+	if target.Role != dqliteClient.Voter || newRole == dqliteClient.Voter {
+		return target, nil
+	}
+
+	if target.Address == leaderAddress {
+		return nil, api.StatusErrorf(http.StatusBadRequest, "Cannot demote the dqlite leader, transfer leadership first")
+	}
+
+	err = validateVoterRemoval(nodes, address, reachable, "demote")
+	if err != nil {
+		return nil, err
+	}
+
+	return target, nil
+}
+
+// validateRoleStability checks that neither dqlite's automatic roles adjustment nor the failure domain rebalancing of
+// the heartbeat would change the role of the dqlite member with the given address once it is assigned the given role.
+// The reachable map holds the reachability of all dqlite members by address, and the cluster members map holds the
+// reachable cluster members by address.
+func validateRoleStability(nodes []dqliteClient.NodeInfo, leaderID uint64, address string, newRole dqliteClient.NodeRole, reachable map[string]bool, clusterMembers map[string]types.ClusterMember) error {
+	var leaderAddress string
+	roles := dqlite.RolesChanges{Config: db.RolesConfig, State: make(map[dqliteClient.NodeInfo]*dqliteClient.NodeMetadata, len(nodes))}
+	for _, node := range nodes {
+		if node.ID == leaderID {
+			leaderAddress = node.Address
+		}
+
+		if node.Address == address {
+			node.Role = newRole
+		}
+
+		// dqlite considers members that it can't reach to be offline, which it marks with nil metadata.
+		var metadata *dqliteClient.NodeMetadata
+		if reachable[node.Address] {
+			metadata = &dqliteClient.NodeMetadata{}
+		}
+
+		roles.State[node] = metadata
+	}
+
+	members := make(map[string]types.ClusterMember, len(clusterMembers))
+	for addr, member := range clusterMembers {
+		if addr == address {
+			member.Role = newRole.String()
+		}
+
+		members[addr] = member
+	}
+
+	promote, demote := planFailureDomainRebalance(members, leaderAddress)
+	for _, member := range []*types.ClusterMember{promote, demote} {
+		if member != nil && member.Address.String() == address {
+			return api.StatusErrorf(http.StatusConflict, "Cannot assign role %q to cluster member with address %q, failure domain rebalancing would change it back", newRole.String(), address)
+		}
+	}
+
+	// Replay the adjustments dqlite would make, one at a time, as long as the member is not a candidate for any of them.
+	// dqlite breaks ties between candidates at random, so the member being a candidate at all means its role may change.
+	for i := 0; i < 2*len(nodes); i++ {
+		role, candidates := roles.Adjust(leaderID)
+		if role == -1 {
+			return nil
+		}
+
+		for _, candidate := range candidates {
+			if candidate.Address == address {
+				return api.StatusErrorf(http.StatusConflict, "Cannot assign role %q to cluster member with address %q, dqlite's roles adjustment would change it to %q", newRole.String(), address, role.String())
+			}
+		}
+
+		metadata := roles.State[candidates[0]]
+		delete(roles.State, candidates[0])
+		candidates[0].Role = role
+		roles.State[candidates[0]] = metadata
+	}
+
+	return nil
+}
@@ -0,0 +1,307 @@
+package resources
+
+import (
+	"fmt"
+	"testing"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+type clusterSuite struct {
+	suite.Suite
+}
+
+func TestClusterSuite(t *testing.T) {
+	suite.Run(t, new(clusterSuite))
+}
+
+func (t *clusterSuite) Test_validateRoleChange() {
+	nodes := []dqliteClient.NodeInfo{
+		{ID: 1, Address: "10.0.0.1:9000", Role: dqliteClient.Voter},
+		{ID: 2, Address: "10.0.0.2:9000", Role: dqliteClient.Voter},
+		{ID: 3, Address: "10.0.0.3:9000", Role: dqliteClient.Voter},
+		{ID: 4, Address: "10.0.0.4:9000", Role: dqliteClient.StandBy},
+		{ID: 5, Address: "10.0.0.5:9000", Role: dqliteClient.Spare},
+	}
+
+	allReachable := map[string]bool{"10.0.0.1:9000": true, "10.0.0.2:9000": true, "10.0.0.3:9000": true, "10.0.0.4:9000": true, "10.0.0.5:9000": true}
+
+	tests := []struct {
+		name      string
+		nodes     []dqliteClient.NodeInfo
+		address   string
+		role      dqliteClient.NodeRole
+		reachable map[string]bool
+		expectID  uint64
+		expectErr bool
+	}{
+		{
+			name:      "Promote stand-by",
+			nodes:     nodes,
+			address:   "10.0.0.4:9000",
+			role:      dqliteClient.Voter,
+			reachable: allReachable,
+			expectID:  4,
+		},
+		{
+			name:      "Promote stand-by with no voters reachable",
+			nodes:     nodes,
+			address:   "10.0.0.4:9000",
+			role:      dqliteClient.Voter,
+			reachable: map[string]bool{},
+			expectID:  4,
+		},
+		{
+			name:      "Demote non-leader voter",
+			nodes:     nodes,
+			address:   "10.0.0.2:9000",
+			role:      dqliteClient.Spare,
+			reachable: allReachable,
+			expectID:  2,
+		},
+		{
+			name:      "Demote non-leader voter with one remaining voter unreachable",
+			nodes:     nodes,
+			address:   "10.0.0.2:9000",
+			role:      dqliteClient.Spare,
+			reachable: map[string]bool{"10.0.0.1:9000": true, "10.0.0.4:9000": true, "10.0.0.5:9000": true},
+			expectErr: true,
+		},
+		{
+			name:      "Demote stand-by",
+			nodes:     nodes,
+			address:   "10.0.0.4:9000",
+			role:      dqliteClient.Spare,
+			reachable: map[string]bool{},
+			expectID:  4,
+		},
+		{
+			name:      "Demote leader",
+			nodes:     nodes,
+			address:   "10.0.0.1:9000",
+			role:      dqliteClient.StandBy,
+			reachable: allReachable,
+			expectErr: true,
+		},
+		{
+			name:      "Demote last voter",
+			nodes:     []dqliteClient.NodeInfo{{ID: 2, Address: "10.0.0.2:9000", Role: dqliteClient.Voter}},
+			address:   "10.0.0.2:9000",
+			role:      dqliteClient.Spare,
+			reachable: allReachable,
+			expectErr: true,
+		},
+		{
+			name:      "Unknown member",
+			nodes:     nodes,
+			address:   "10.0.0.6:9000",
+			role:      dqliteClient.Voter,
+			reachable: allReachable,
+			expectErr: true,
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		node, err := validateRoleChange(c.nodes, "10.0.0.1:9000", c.address, c.role, c.reachable)
+		if c.expectErr {
+			t.Error(err)
+			continue
+		}
+
+		t.NoError(err)
+		t.Equal(c.expectID, node.ID)
+	}
+}
+
+func (t *clusterSuite) Test_validateRoleStability() {
+	node := func(i uint64, role dqliteClient.NodeRole) dqliteClient.NodeInfo {
+		return dqliteClient.NodeInfo{ID: i, Address: fmt.Sprintf("10.0.0.%d:9000", i), Role: role}
+	}
+
+	toMembers := func(nodes []dqliteClient.NodeInfo, domains ...string) map[string]types.ClusterMember {
+		members := make(map[string]types.ClusterMember, len(nodes))
+		for i, node := range nodes {
+			addr, err := types.ParseAddrPort(node.Address)
+			t.Require().NoError(err)
+
+			members[node.Address] = types.ClusterMember{
+				ClusterMemberLocal: types.ClusterMemberLocal{Name: fmt.Sprintf("n%d", node.ID), Address: addr},
+				Role:               node.Role.String(),
+				FailureDomain:      domains[i],
+			}
+		}
+
+		return members
+	}
+
+	threeVoters := []dqliteClient.NodeInfo{node(1, dqliteClient.Voter), node(2, dqliteClient.Voter), node(3, dqliteClient.Voter)}
+	withSpare := append(threeVoters, node(4, dqliteClient.Spare))
+	withStandBy := append(threeVoters, node(4, dqliteClient.StandBy))
+	allReachable := map[string]bool{"10.0.0.1:9000": true, "10.0.0.2:9000": true, "10.0.0.3:9000": true, "10.0.0.4:9000": true}
+	thirdOffline := map[string]bool{"10.0.0.1:9000": true, "10.0.0.2:9000": true, "10.0.0.4:9000": true}
+
+	tests := []struct {
+		name      string
+		nodes     []dqliteClient.NodeInfo
+		domains   []string
+		address   string
+		role      dqliteClient.NodeRole
+		reachable map[string]bool
+		expectErr bool
+	}{
+		{
+			name:      "Demote voter that dqlite would promote back",
+			nodes:     threeVoters,
+			domains:   []string{"", "", ""},
+			address:   "10.0.0.3:9000",
+			role:      dqliteClient.Spare,
+			reachable: allReachable,
+			expectErr: true,
+		},
+		{
+			name:      "Promote spare in a cluster too small for more voters",
+			nodes:     []dqliteClient.NodeInfo{node(1, dqliteClient.Voter), node(2, dqliteClient.Spare)},
+			domains:   []string{"", ""},
+			address:   "10.0.0.2:9000",
+			role:      dqliteClient.Voter,
+			reachable: allReachable,
+			expectErr: true,
+		},
+		{
+			name:      "Promote spare beyond the target number of voters",
+			nodes:     withSpare,
+			domains:   []string{"", "", "", ""},
+			address:   "10.0.0.4:9000",
+			role:      dqliteClient.Voter,
+			reachable: allReachable,
+			expectErr: true,
+		},
+		{
+			name:      "Promote spare to stand-by",
+			nodes:     withSpare,
+			domains:   []string{"", "", "", ""},
+			address:   "10.0.0.4:9000",
+			role:      dqliteClient.StandBy,
+			reachable: allReachable,
+		},
+		{
+			name:      "Demote offline voter",
+			nodes:     withStandBy,
+			domains:   []string{"", "", "", ""},
+			address:   "10.0.0.3:9000",
+			role:      dqliteClient.Spare,
+			reachable: thirdOffline,
+		},
+		{
+			name:      "Promote spare to stand-by that failure domain rebalancing would promote to voter",
+			nodes:     withSpare,
+			domains:   []string{"a", "a", "b", "c"},
+			address:   "10.0.0.4:9000",
+			role:      dqliteClient.StandBy,
+			reachable: allReachable,
+			expectErr: true,
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		members := toMembers(c.nodes, c.domains...)
+		for addr := range members {
+			if !c.reachable[addr] {
+				delete(members, addr)
+			}
+		}
+
+		err := validateRoleStability(c.nodes, 1, c.address, c.role, c.reachable, members)
+		if c.expectErr {
+			t.Error(err)
+			continue
+		}
+
+		t.NoError(err)
+	}
+}
+
+func (t *clusterSuite) Test_parseDqliteRole() {
+	for _, role := range []dqliteClient.NodeRole{dqliteClient.Voter, dqliteClient.StandBy, dqliteClient.Spare} {
+		parsed, err := parseDqliteRole(role.String())
+		t.NoError(err)
+		t.Equal(role, parsed)
+	}
+
+	_, err := parseDqliteRole("PENDING")
+	t.Error(err)
+}
+
+func (t *clusterSuite) Test_validateAddressChange() {
+	nodes := []dqliteClient.NodeInfo{
+		{ID: 1, Address: "10.0.0.1:9000", Role: dqliteClient.Voter},
+		{ID: 2, Address: "10.0.0.2:9000", Role: dqliteClient.Voter},
+		{ID: 3, Address: "10.0.0.3:9000", Role: dqliteClient.Voter},
+		{ID: 4, Address: "10.0.0.4:9000", Role: dqliteClient.Spare},
+	}
+
+	tests := []struct {
+		name      string
+		nodes     []dqliteClient.NodeInfo
+		address   string
+		reachable map[string]bool
+		expectID  uint64
+		expectErr bool
+	}{
+		{
+			name:      "Move voter with all voters reachable",
+			nodes:     nodes,
+			address:   "10.0.0.1:9000",
+			reachable: map[string]bool{"10.0.0.2:9000": true, "10.0.0.3:9000": true, "10.0.0.4:9000": true},
+			expectID:  1,
+		},
+		{
+			name:      "Move spare with no voters reachable",
+			nodes:     nodes,
+			address:   "10.0.0.4:9000",
+			reachable: map[string]bool{},
+			expectID:  4,
+		},
+		{
+			name:      "Move voter with one remaining voter unreachable",
+			nodes:     nodes,
+			address:   "10.0.0.1:9000",
+			reachable: map[string]bool{"10.0.0.2:9000": true, "10.0.0.4:9000": true},
+			expectErr: true,
+		},
+		{
+			name:      "Move last voter",
+			nodes:     []dqliteClient.NodeInfo{{ID: 1, Address: "10.0.0.1:9000", Role: dqliteClient.Voter}},
+			address:   "10.0.0.1:9000",
+			reachable: map[string]bool{},
+			expectErr: true,
+		},
+		{
+			name:      "Unknown member",
+			nodes:     nodes,
+			address:   "10.0.0.5:9000",
+			reachable: map[string]bool{},
+			expectErr: true,
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		node, err := validateAddressChange(c.nodes, c.address, c.reachable)
+		if c.expectErr {
+			t.Error(err)
+			continue
+		}
+
+		t.NoError(err)
+		t.Equal(c.expectID, node.ID)
+	}
+}
@@ -0,0 +1,158 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/canonical/lxd/lxd/response"
+
+	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/cluster"
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var clusterConfigCmd = rest.Endpoint{
+	Path: "config",
+
+	Get:   rest.EndpointAction{Handler: clusterConfigGet, AccessHandler: access.AllowAuthenticated},
+	Patch: rest.EndpointAction{Handler: clusterConfigPatch, AccessHandler: access.AllowAuthenticated},
+}
+
+func clusterConfigGet(s state.State, r *http.Request) response.Response {
+	var config types.ClusterConfig
+	err := s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		config, err = cluster.GetCoreConfig(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, config)
+}
+
+// clusterConfigPatch applies the given changes to the cluster-wide configuration, and runs the OnClusterConfigUpdate
+// hook on all cluster members if any value has changed. Keys with an empty value are removed from the configuration.
+func clusterConfigPatch(s state.State, r *http.Request) response.Response {
+	req := types.ClusterConfig{}
+
+	// Parse the request.
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	err = validateClusterConfig(intState.ClusterConfigKeys, req)
+	if err != nil {
+		return response.BadRequest(err)
+	}
+
+	var config types.ClusterConfig
+	err = s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		current, err := cluster.GetCoreConfig(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		changes := types.ClusterConfig{}
+		for key, value := range req {
+			if current[key] != value {
+				changes[key] = value
+			}
+		}
+
+		if len(changes) == 0 {
+			return nil
+		}
+
+		err = cluster.UpdateCoreConfig(ctx, tx, changes)
+		if err != nil {
+			return err
+		}
+
+		config, err = cluster.GetCoreConfig(ctx, tx)
+
+		return err
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Nothing changed, so there is no need to run any hooks.
+	if config == nil {
+		return response.EmptySyncResponse
+	}
+
+	// Run the OnClusterConfigUpdate hook locally.
+	hookCtx, hookCancel := context.WithCancel(r.Context())
+	err = intState.Hooks.OnClusterConfigUpdate(hookCtx, s, config)
+	hookCancel()
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to run hook on %q after cluster config update: %w", s.Name(), err))
+	}
+
+	cluster, err := s.Cluster(false)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	// Run the OnClusterConfigUpdate hook on all other members.
+	remotes := s.Remotes()
+	err = cluster.Query(r.Context(), true, func(ctx context.Context, c *client.Client) error {
+		c.SetClusterNotification()
+		addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+		if err != nil {
+			return err
+		}
+
+		remote := remotes.RemoteByAddress(addrPort)
+		if remote == nil {
+			return fmt.Errorf("No remote found at address %q to run the %q hook", c.URL().URL.Host, internalTypes.OnClusterConfigUpdate)
+		}
+
+		return internalClient.RunOnClusterConfigUpdateHook(ctx, c.Client.UseTarget(remote.Name), config)
+	})
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.EmptySyncResponse
+}
+
+// validateClusterConfig checks that each key in the given config is supported, and that its value passes validation.
+// Empty values are always accepted as they unset the key.
+func validateClusterConfig(validators map[string]func(value string) error, config types.ClusterConfig) error {
+	for key, value := range config {
+		validator, ok := validators[key]
+		if !ok {
+			return fmt.Errorf("Unknown cluster configuration key %q", key)
+		}
+
+		if value == "" || validator == nil {
+			continue
+		}
+
+		err := validator(value)
+		if err != nil {
+			return fmt.Errorf("Invalid value for cluster configuration key %q: %w", key, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/canonical/lxd/shared/validate"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+type configSuite struct {
+	suite.Suite
+}
+
+func TestConfigSuite(t *testing.T) {
+	suite.Run(t, new(configSuite))
+}
+
+func (t *configSuite) Test_validateClusterConfig() {
+	validators := map[string]func(value string) error{
+		"any":  nil,
+		"bool": validate.IsBool,
+	}
+
+	tests := []struct {
+		name      string
+		config    types.ClusterConfig
+		expectErr bool
+	}{
+		{
+			name:   "Valid values",
+			config: types.ClusterConfig{"any": "foo", "bool": "true"},
+		},
+		{
+			name:   "Unset a key",
+			config: types.ClusterConfig{"bool": ""},
+		},
+		{
+			name:      "Invalid value",
+			config:    types.ClusterConfig{"bool": "maybe"},
+			expectErr: true,
+		},
+		{
+			name:      "Unknown key",
+			config:    types.ClusterConfig{"unknown": "foo"},
+			expectErr: true,
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		err := validateClusterConfig(validators, c.config)
+		if c.expectErr {
+			t.Error(err)
+		} else {
+			t.NoError(err)
+		}
+	}
+}
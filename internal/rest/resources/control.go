@@ -49,6 +49,10 @@ func controlPost(state state.State, r *http.Request) response.Response {
 		return response.SmartError(fmt.Errorf("Invalid options - received join token and bootstrap flag"))
 	}
 
+	if !req.Bootstrap && req.RestoreDump != "" {
+		return response.SmartError(fmt.Errorf("Invalid options - a database backup can only be restored when bootstrapping"))
+	}
+
 	err = utils.ValidateFQDN(req.Name)
 	if err != nil {
 		return response.SmartError(fmt.Errorf("Cluster member name %q is not a valid FQDN: %w", req.Name, err))
@@ -59,6 +63,7 @@ func controlPost(state state.State, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
+	intState.LocalConfig().SetFailureDomain(req.FailureDomain)
 	daemonConfig := trust.Location{Address: req.Address, Name: req.Name}
 	err = intState.SetConfig(daemonConfig)
 	if err != nil {
@@ -162,6 +167,10 @@ func controlPost(state state.State, r *http.Request) response.Response {
 		return response.EmptySyncResponse
 	}
 
+	if req.RestoreDump != "" {
+		intState.InternalDatabase.SetRestore(req.RestoreDump)
+	}
+
 	err = intState.StartAPI(r.Context(), req.Bootstrap, req.InitConfig)
 	if err != nil {
 		return response.SmartError(err)
@@ -207,6 +216,8 @@ func joinWithToken(state state.State, r *http.Request, req *internalTypes.Contro
 		SchemaExternalVersion: externalVersion,
 		Secret:                token.Secret,
 		Extensions:            intState.Extensions,
+		FailureDomain:         req.FailureDomain,
+		Version:               state.Version(),
 	}
 
 	// Get a client to the target address.
@@ -9,6 +9,7 @@ import (
 
 	"github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
 )
 
 var databaseCmd = rest.Endpoint{
@@ -19,6 +20,73 @@ var databaseCmd = rest.Endpoint{
 	Patch: rest.EndpointAction{Handler: databasePatch},
 }
 
+var databaseInfoCmd = rest.Endpoint{
+	AllowedBeforeInit: true,
+	Path:              "database",
+
+	Get: rest.EndpointAction{Handler: databaseInfoGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+// databaseInfoGet returns diagnostic information about the database, as seen from this cluster member.
+func databaseInfoGet(s state.State, r *http.Request) response.Response {
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	info, err := intState.InternalDatabase.Info(r.Context())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, info)
+}
+
+var databaseSchemaCmd = rest.Endpoint{
+	AllowedBeforeInit: true,
+	Path:              "database/schema",
+
+	Get: rest.EndpointAction{Handler: databaseSchemaGet, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+var databaseSchemaDryRunCmd = rest.Endpoint{
+	AllowedBeforeInit: true,
+	Path:              "database/schema/dry-run",
+
+	Post: rest.EndpointAction{Handler: databaseSchemaDryRunPost, AccessHandler: access.AllowAuthenticated, ProxyTarget: true},
+}
+
+// databaseSchemaGet returns the applied and pending schema updates, as seen from this cluster member.
+func databaseSchemaGet(s state.State, r *http.Request) response.Response {
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	status, err := intState.InternalDatabase.SchemaStatus(r.Context())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, status)
+}
+
+// databaseSchemaDryRunPost applies the pending schema updates of this cluster member without committing them, and
+// returns the updates that were applied.
+func databaseSchemaDryRunPost(s state.State, r *http.Request) response.Response {
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	updates, err := intState.InternalDatabase.SchemaDryRun(r.Context())
+	if err != nil {
+		return response.SmartError(err)
+	}
+
+	return response.SyncResponse(true, updates)
+}
+
 func databasePost(state state.State, r *http.Request) response.Response {
 	// Compare the dqlite version of the connecting client with our own.
 	versionHeader := r.Header.Get("X-Dqlite-Version")
@@ -6,14 +6,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
 	"time"
 
+	dqliteClient "github.com/canonical/go-dqlite/client"
 	"github.com/canonical/lxd/lxd/response"
 	"github.com/canonical/lxd/shared/logger"
 
 	"github.com/canonical/microcluster/v3/client"
 	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/db"
+	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/rest"
@@ -77,16 +81,31 @@ func heartbeatPost(s state.State, r *http.Request) response.Response {
 		return response.SmartError(err)
 	}
 
-	if internalSchemaVersion != hbInfo.MaxSchemaInternal || externalSchemaVersion != hbInfo.MaxSchemaExternal {
-		err := intState.InternalDatabase.Update()
+	// If our schema version is behind, trigger an upgrade of this cluster member.
+	if internalSchemaVersion < hbInfo.MaxSchemaInternal || externalSchemaVersion < hbInfo.MaxSchemaExternal {
+		intState.InternalDatabase.RequireUpgrade(fmt.Sprintf("Schema version %d.%d is behind the cluster's %d.%d", internalSchemaVersion, externalSchemaVersion, hbInfo.MaxSchemaInternal, hbInfo.MaxSchemaExternal))
+	}
+
+	// Scheduled backups are kept by the cluster member that was leader when taking them, so apply the retention rules to
+	// our own backups too. Skip them if we are still taking a backup from when we were the leader.
+	if intState.BackupInterval > 0 && scheduledBackupMu.TryLock() {
+		err = intState.InternalDatabase.PruneBackups(intState.BackupKeepLast, intState.BackupMaxAge)
+		scheduledBackupMu.Unlock()
 		if err != nil {
-			return response.SmartError(err)
+			logger.Warn("Failed to prune scheduled database backups", logger.Ctx{"error": err})
 		}
 	}
 
-	// TODO: If our schema version is behind, we should try to update here.
+	hookCtx, hookCancel := context.WithCancel(r.Context())
+	payload, err := intState.Hooks.HeartbeatPayload(hookCtx, s)
+	hookCancel()
+	if err != nil {
+		// Still respond to the heartbeat, as the leader would otherwise consider this cluster member unreachable.
+		logger.Error("Failed to run HeartbeatPayload hook", logger.Ctx{"error": err})
+		payload = nil
+	}
 
-	return response.EmptySyncResponse
+	return response.SyncResponse(true, internalTypes.HeartbeatResponse{Version: s.Version(), Payload: payload})
 }
 
 // beginHeartbeat initiates a heartbeat from the leader node to all other cluster members, if we haven't sent one out
@@ -172,9 +191,11 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 	leaderEntry.LastHeartbeat = time.Now()
 	clusterMap[s.Address().URL.Host] = leaderEntry
 
-	// Record the maximum schema version discovered.
-	hbInfo := internalTypes.HeartbeatInfo{ClusterMembers: clusterMap}
+	// Record the maximum schema version discovered, and pass along the heartbeat payloads from the last round.
+	hbInfo := internalTypes.HeartbeatInfo{ClusterMembers: clusterMap, MemberPayloads: make(map[string]types.HeartbeatPayload, len(clusterMembers))}
 	for _, node := range clusterMembers {
+		hbInfo.MemberPayloads[node.Name] = node.HeartbeatPayload
+
 		if node.SchemaInternalVersion > hbInfo.MaxSchemaInternal {
 			hbInfo.MaxSchemaInternal = node.SchemaInternalVersion
 		}
@@ -189,6 +210,27 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 		return response.SmartError(err)
 	}
 
+	// Keep track of the cluster members that responded to a heartbeat recently, starting with ourselves.
+	reachable := map[string]bool{s.Address().URL.Host: true}
+
+	// Keep track of the round-trip time of each heartbeat, or the error it failed with.
+	latencies := map[string]time.Duration{s.Address().URL.Host: 0}
+	heartbeatErrors := map[string]string{}
+
+	// Keep track of the versions reported this round, starting with our own.
+	versions := map[string]string{s.Address().URL.Host: s.Version()}
+
+	// Keep track of the heartbeat payloads received this round, starting with our own.
+	payloads := map[string]types.HeartbeatPayload{}
+	hookCtx, hookCancel := context.WithCancel(ctx)
+	leaderPayload, err := intState.Hooks.HeartbeatPayload(hookCtx, s)
+	hookCancel()
+	if err != nil {
+		logger.Error("Failed to run HeartbeatPayload hook", logger.Ctx{"error": err})
+	} else {
+		payloads[s.Address().URL.Host] = leaderPayload
+	}
+
 	// Use a lock to handle concurrent access to hbInfo.
 	mapLock := sync.RWMutex{}
 	// Send heartbeat to non-leader members, updating their local member cache and updating the node.
@@ -207,12 +249,23 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 		timeSinceLast := time.Since(currentMember.LastHeartbeat)
 		if timeSinceLast < time.Duration(intState.InternalDatabase.GetHeartbeatInterval()) {
 			logger.Warnf("Skipping heartbeat to %q, one was sent %q ago", currentMember.Name, timeSinceLast.String())
+
+			mapLock.Lock()
+			reachable[addr] = true
+			mapLock.Unlock()
+
 			return nil
 		}
 
-		err := intState.InternalDatabase.SendHeartbeat(ctx, &c.Client, hbInfo)
+		start := time.Now()
+		hbResp, err := intState.InternalDatabase.SendHeartbeat(ctx, &c.Client, hbInfo)
 		if err != nil {
 			logger.Error("Received error sending heartbeat to cluster member", logger.Ctx{"target": addr, "error": err})
+
+			mapLock.Lock()
+			heartbeatErrors[addr] = err.Error()
+			mapLock.Unlock()
+
 			return nil
 		}
 
@@ -220,6 +273,10 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 
 		mapLock.Lock()
 		hbInfo.ClusterMembers[addr] = currentMember
+		reachable[addr] = true
+		payloads[addr] = hbResp.Payload
+		versions[addr] = hbResp.Version
+		latencies[addr] = currentMember.LastHeartbeat.Sub(start)
 		mapLock.Unlock()
 
 		return nil
@@ -228,9 +285,20 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 		return response.SmartError(err)
 	}
 
+	// Spread voters across failure domains, recording any role changes so they are written to the database below.
+	err = rebalanceFailureDomains(ctx, s, hbInfo.ClusterMembers, reachable)
+	if err != nil {
+		logger.Error("Failed to rebalance dqlite roles across failure domains", logger.Ctx{"error": err})
+	}
+
 	// Having sent a heartbeat to each valid cluster member, update the database record of members.
 	roleStatusMap := map[string]types.RoleStatus{}
+	payloadMap := map[string]types.HeartbeatPayload{}
+	statusChanges := []types.ClusterMember{}
+	updatedMembers := map[string]types.ClusterMember{}
 	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		statusChanges = []types.ClusterMember{}
+		updatedMembers = map[string]types.ClusterMember{}
 		dbClusterMembers, err := cluster.GetCoreClusterMembers(ctx, tx)
 		if err != nil {
 			return err
@@ -248,12 +316,62 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 				New: heartbeatInfo.Role,
 			}
 
+			// Count consecutive heartbeat failures, and record any resulting change in heartbeat status.
+			oldStatus := clusterMember.HeartbeatStatus
+			if oldStatus == "" {
+				oldStatus = types.MemberOnline
+			}
+
+			if reachable[clusterMember.Address] {
+				clusterMember.HeartbeatFailures = 0
+			} else {
+				clusterMember.HeartbeatFailures++
+			}
+
+			// Record the heartbeat latency, clearing any previous error, or the error of a failed heartbeat.
+			latency, ok := latencies[clusterMember.Address]
+			if ok {
+				clusterMember.HeartbeatLatency = latency
+				clusterMember.HeartbeatError = ""
+			}
+
+			heartbeatError, ok := heartbeatErrors[clusterMember.Address]
+			if ok {
+				clusterMember.HeartbeatError = heartbeatError
+			}
+
+			// Keep the last known payload of cluster members that did not respond this round.
+			payload, ok := payloads[clusterMember.Address]
+			if ok {
+				clusterMember.HeartbeatPayload = payload
+			}
+
+			// Keep the last known version of cluster members that did not respond this round, or did not report one.
+			version := versions[clusterMember.Address]
+			if version != "" {
+				clusterMember.Version = version
+			}
+
+			// Store heartbeat payloads for OnHeartbeatPayloads hook.
+			payloadMap[clusterMember.Name] = clusterMember.HeartbeatPayload
+
+			clusterMember.HeartbeatStatus = heartbeatStatus(clusterMember.HeartbeatFailures, intState.HeartbeatSuspectThreshold, intState.HeartbeatOfflineThreshold)
 			clusterMember.Heartbeat = heartbeatInfo.LastHeartbeat
 			clusterMember.Role = cluster.Role(heartbeatInfo.Role)
 			err = cluster.UpdateCoreClusterMember(ctx, tx, clusterMember.Name, clusterMember)
 			if err != nil {
 				return err
 			}
+
+			apiClusterMember, err := clusterMember.ToAPI()
+			if err != nil {
+				return err
+			}
+
+			updatedMembers[clusterMember.Address] = *apiClusterMember
+			if clusterMember.HeartbeatStatus != oldStatus {
+				statusChanges = append(statusChanges, *apiClusterMember)
+			}
 		}
 
 		return cluster.DeleteExpiredCoreTokenRecords(ctx, tx)
@@ -262,12 +380,354 @@ func beginHeartbeat(ctx context.Context, s state.State, hbReq internalTypes.Hear
 		return response.SmartError(err)
 	}
 
-	hookCtx, hookCancel := context.WithCancel(ctx)
+	for _, member := range statusChanges {
+		logger.Warn("Cluster member heartbeat status changed", logger.Ctx{"name": member.Name, "status": member.HeartbeatStatus, "failures": member.HeartbeatFailures})
+
+		hookType := internalTypes.OnMemberDown
+		if member.HeartbeatStatus == types.MemberOnline {
+			hookType = internalTypes.OnMemberUp
+		}
+
+		err = runMemberHook(ctx, s, hookType, member, reachable)
+		if err != nil {
+			logger.Error("Failed to run cluster member status hooks", logger.Ctx{"name": member.Name, "error": err})
+		}
+	}
+
+	evictMember := planAutoEviction(updatedMembers, time.Now(), intState.AutoEvictAfter, intState.AutoEvictMinVoters, s.Address().URL.Host)
+	if evictMember != nil {
+		// Removal waits on the unreachable member, so evict it in the background instead of holding up the heartbeat round.
+		if autoEvictMu.TryLock() {
+			go func() {
+				defer autoEvictMu.Unlock()
+
+				err := evictClusterMember(intState.Context, s, *evictMember, reachable)
+				if err != nil {
+					logger.Error("Failed to evict offline cluster member", logger.Ctx{"name": evictMember.Name, "error": err})
+				}
+			}()
+		}
+	}
+
+	// Backups may outlast a heartbeat round, so take any scheduled backup in the background.
+	if intState.BackupInterval > 0 && scheduledBackupMu.TryLock() {
+		go func() {
+			defer scheduledBackupMu.Unlock()
+
+			err := intState.InternalDatabase.ScheduledBackup(intState.Context, s.Version(), intState.BackupInterval, intState.BackupKeepLast, intState.BackupMaxAge)
+			if err != nil {
+				logger.Error("Failed to take scheduled database backup", logger.Ctx{"error": err})
+			}
+		}()
+	}
+
+	// Remove changes that watchers have long since received from the change log.
+	err = intState.InternalDatabase.PruneChangeLog(ctx, db.DefaultChangeLogRetention)
+	if err != nil {
+		logger.Warn("Failed to prune database change log", logger.Ctx{"error": err})
+	}
+
+	hookCtx, hookCancel = context.WithCancel(ctx)
 	err = intState.Hooks.OnHeartbeat(hookCtx, s, roleStatusMap)
 	hookCancel()
 	if err != nil {
 		return response.SmartError(err)
 	}
 
+	hookCtx, hookCancel = context.WithCancel(ctx)
+	err = intState.Hooks.OnHeartbeatPayloads(hookCtx, s, payloadMap)
+	hookCancel()
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	return response.EmptySyncResponse
 }
+
+// heartbeatStatus returns the heartbeat status of a cluster member that has missed the given number of consecutive heartbeats.
+func heartbeatStatus(failures int, suspectThreshold int, offlineThreshold int) types.MemberStatus {
+	if failures >= offlineThreshold {
+		return types.MemberOffline
+	}
+
+	if failures >= suspectThreshold && failures > 0 {
+		return types.MemberSuspect
+	}
+
+	return types.MemberOnline
+}
+
+// autoEvictMu ensures only one automatic eviction runs at a time, as it may outlast a heartbeat round.
+var autoEvictMu sync.Mutex
+
+// scheduledBackupMu ensures only one scheduled backup runs at a time, as it may outlast a heartbeat round, and that
+// backups are not pruned while one is being taken.
+var scheduledBackupMu sync.Mutex
+
+// planAutoEviction returns the cluster member that should be automatically evicted, or nil if there is none.
+// A cluster member is evicted once it has been offline for at least evictAfter since its last successful heartbeat,
+// as long as at least minVoters voters that are not offline would remain. At most one member is evicted per round,
+// and the leader is never evicted.
+func planAutoEviction(clusterMembers map[string]types.ClusterMember, now time.Time, evictAfter time.Duration, minVoters int, leaderAddress string) *types.ClusterMember {
+	if evictAfter <= 0 {
+		return nil
+	}
+
+	onlineVoters := 0
+	names := make([]string, 0, len(clusterMembers))
+	members := make(map[string]types.ClusterMember, len(clusterMembers))
+	for _, member := range clusterMembers {
+		if member.Role == dqliteClient.Voter.String() && member.HeartbeatStatus != types.MemberOffline {
+			onlineVoters++
+		}
+
+		names = append(names, member.Name)
+		members[member.Name] = member
+	}
+
+	// Offline members don't count towards the online voters, so evicting one never lowers that count.
+	if onlineVoters < minVoters {
+		return nil
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		member := members[name]
+		if member.HeartbeatStatus != types.MemberOffline || member.Address.String() == leaderAddress {
+			continue
+		}
+
+		// Members that never completed a heartbeat have no record of when they went offline.
+		if member.LastHeartbeat.IsZero() || now.Sub(member.LastHeartbeat) < evictAfter {
+			continue
+		}
+
+		return &member
+	}
+
+	return nil
+}
+
+// evictClusterMember forcibly removes the given offline cluster member through the leader, as if it were removed with
+// `cluster remove --force`, and then runs the OnMemberEvicted hook locally and on all reachable cluster members.
+func evictClusterMember(ctx context.Context, s state.State, member types.ClusterMember, reachable map[string]bool) error {
+	logger.Warn("Evicting offline cluster member", logger.Ctx{"name": member.Name, "address": member.Address, "lastHeartbeat": member.LastHeartbeat, "failures": member.HeartbeatFailures})
+
+	localClient, err := internalClient.New(s.FileSystem().ControlSocket(), nil, nil, false)
+	if err != nil {
+		return err
+	}
+
+	err = localClient.DeleteClusterMember(ctx, member.Name, true)
+	if err != nil {
+		return fmt.Errorf("Failed to remove cluster member %q: %w", member.Name, err)
+	}
+
+	return runMemberHook(ctx, s, internalTypes.OnMemberEvicted, member, reachable)
+}
+
+// runMemberHook runs the given OnMemberDown, OnMemberUp or OnMemberEvicted hook for the given cluster member locally
+// and on all other cluster members that responded to the last heartbeat.
+func runMemberHook(ctx context.Context, s state.State, hookType internalTypes.HookType, member types.ClusterMember, reachable map[string]bool) error {
+	intState, err := internalState.ToInternal(s)
+	if err != nil {
+		return err
+	}
+
+	var runHook func(ctx context.Context, s state.State, member types.ClusterMember) error
+	var runRemoteHook func(ctx context.Context, c *internalClient.Client, config internalTypes.HookMemberStatusOptions) error
+	switch hookType {
+	case internalTypes.OnMemberDown:
+		runHook = intState.Hooks.OnMemberDown
+		runRemoteHook = internalClient.RunOnMemberDownHook
+	case internalTypes.OnMemberUp:
+		runHook = intState.Hooks.OnMemberUp
+		runRemoteHook = internalClient.RunOnMemberUpHook
+	case internalTypes.OnMemberEvicted:
+		runHook = intState.Hooks.OnMemberEvicted
+		runRemoteHook = internalClient.RunOnMemberEvictedHook
+	default:
+		return fmt.Errorf("Invalid cluster member hook type %q", hookType)
+	}
+
+	hookCtx, hookCancel := context.WithCancel(ctx)
+	err = runHook(hookCtx, s, member)
+	hookCancel()
+	if err != nil {
+		return fmt.Errorf("Failed to run %q hook on %q: %w", hookType, s.Name(), err)
+	}
+
+	clusterClients, err := s.Cluster(false)
+	if err != nil {
+		return err
+	}
+
+	remotes := s.Remotes()
+	return clusterClients.Query(ctx, true, func(ctx context.Context, c *client.Client) error {
+		// Skip cluster members that did not respond to the heartbeat, as they would only delay the others.
+		if !reachable[c.URL().URL.Host] {
+			return nil
+		}
+
+		c.SetClusterNotification()
+		addrPort, err := types.ParseAddrPort(c.URL().URL.Host)
+		if err != nil {
+			return err
+		}
+
+		remote := remotes.RemoteByAddress(addrPort)
+		if remote == nil {
+			return fmt.Errorf("No remote found at address %q to run the %q hook", c.URL().URL.Host, hookType)
+		}
+
+		return runRemoteHook(ctx, c.Client.UseTarget(remote.Name), internalTypes.HookMemberStatusOptions{Member: member})
+	})
+}
+
+// rebalanceFailureDomains swaps the role of a voter in the failure domain with the most voters with that of a reachable
+// stand-by or spare in a failure domain with fewer voters, so that losing a single failure domain does not break quorum.
+// At most one swap is performed per heartbeat round, and the given map of cluster members is updated with the new roles.
+// dqlite is not told about failure domains, as its own roles adjustment would break ties differently and undo these swaps.
+func rebalanceFailureDomains(ctx context.Context, s state.State, clusterMembers map[string]types.ClusterMember, reachable map[string]bool) error {
+	candidates := make(map[string]types.ClusterMember, len(clusterMembers))
+	for addr, member := range clusterMembers {
+		if reachable[addr] {
+			candidates[addr] = member
+		}
+	}
+
+	promote, demote := planFailureDomainRebalance(candidates, s.Address().URL.Host)
+	if promote == nil || demote == nil {
+		return nil
+	}
+
+	leader, err := s.Database().Leader(ctx)
+	if err != nil {
+		return err
+	}
+
+	info, err := leader.Cluster(ctx)
+	if err != nil {
+		return err
+	}
+
+	nodeIDs := make(map[string]uint64, len(info))
+	for _, node := range info {
+		nodeIDs[node.Address] = node.ID
+	}
+
+	promoteID, ok := nodeIDs[promote.Address.String()]
+	if !ok {
+		return fmt.Errorf("No dqlite record exists for cluster member %q", promote.Name)
+	}
+
+	demoteID, ok := nodeIDs[demote.Address.String()]
+	if !ok {
+		return fmt.Errorf("No dqlite record exists for cluster member %q", demote.Name)
+	}
+
+	demoteRole, err := parseDqliteRole(promote.Role)
+	if err != nil {
+		return err
+	}
+
+	logger.Info("Rebalancing dqlite voters across failure domains", logger.Ctx{"promote": promote.Name, "promoteDomain": promote.FailureDomain, "demote": demote.Name, "demoteDomain": demote.FailureDomain})
+
+	// Promote before demoting so that the number of voters never drops below its current value.
+	err = leader.Assign(ctx, promoteID, dqliteClient.Voter)
+	if err != nil {
+		return fmt.Errorf("Failed to promote cluster member %q: %w", promote.Name, err)
+	}
+
+	promote.Role = dqliteClient.Voter.String()
+	clusterMembers[promote.Address.String()] = *promote
+
+	err = leader.Assign(ctx, demoteID, demoteRole)
+	if err != nil {
+		return fmt.Errorf("Failed to demote cluster member %q: %w", demote.Name, err)
+	}
+
+	demote.Role = demoteRole.String()
+	clusterMembers[demote.Address.String()] = *demote
+
+	return nil
+}
+
+// planFailureDomainRebalance returns a pair of cluster members whose roles should be swapped to better spread voters
+// across failure domains, or nil if no such swap would help. Cluster members without a failure domain are considered
+// to share the same one, and the leader is never demoted.
+func planFailureDomainRebalance(clusterMembers map[string]types.ClusterMember, leaderAddress string) (promote *types.ClusterMember, demote *types.ClusterMember) {
+	voterCount := 0
+	domainVoters := map[string]int{}
+	for _, member := range clusterMembers {
+		_, ok := domainVoters[member.FailureDomain]
+		if !ok {
+			domainVoters[member.FailureDomain] = 0
+		}
+
+		if member.Role == dqliteClient.Voter.String() {
+			domainVoters[member.FailureDomain]++
+			voterCount++
+		}
+	}
+
+	// Quorum can't survive the loss of any voter with fewer than 3 voters, so there is nothing to balance.
+	if voterCount < 3 || len(domainVoters) < 2 {
+		return nil, nil
+	}
+
+	// Find the failure domain holding the most voters.
+	var maxDomain string
+	for domain, count := range domainVoters {
+		if count > domainVoters[maxDomain] || (count == domainVoters[maxDomain] && domain < maxDomain) {
+			maxDomain = domain
+		}
+	}
+
+	// Losing the failure domain with the most voters still leaves a majority.
+	if domainVoters[maxDomain]*2 < voterCount {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(clusterMembers))
+	members := make(map[string]types.ClusterMember, len(clusterMembers))
+	for _, member := range clusterMembers {
+		names = append(names, member.Name)
+		members[member.Name] = member
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		member := members[name]
+		switch member.Role {
+		case dqliteClient.Voter.String():
+			if demote == nil && member.FailureDomain == maxDomain && member.Address.String() != leaderAddress {
+				demote = &member
+			}
+
+		case dqliteClient.StandBy.String(), dqliteClient.Spare.String():
+			// Only consider members whose failure domain would still hold fewer voters than the largest one.
+			if domainVoters[member.FailureDomain]+1 >= domainVoters[maxDomain] {
+				continue
+			}
+
+			if promote == nil {
+				promote = &member
+				continue
+			}
+
+			// Prefer stand-by members as they are already up to date, then those in the least populated failure domain.
+			isStandBy := member.Role == dqliteClient.StandBy.String()
+			wasStandBy := promote.Role == dqliteClient.StandBy.String()
+			if (isStandBy && !wasStandBy) || (isStandBy == wasStandBy && domainVoters[member.FailureDomain] < domainVoters[promote.FailureDomain]) {
+				promote = &member
+			}
+		}
+	}
+
+	if promote == nil || demote == nil {
+		return nil, nil
+	}
+
+	return promote, demote
+}
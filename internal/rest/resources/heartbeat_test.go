@@ -0,0 +1,342 @@
+package resources
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	dqliteClient "github.com/canonical/go-dqlite/client"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+type heartbeatSuite struct {
+	suite.Suite
+}
+
+func TestHeartbeatSuite(t *testing.T) {
+	suite.Run(t, new(heartbeatSuite))
+}
+
+func (t *heartbeatSuite) Test_planFailureDomainRebalance() {
+	member := func(i int, role dqliteClient.NodeRole, domain string) types.ClusterMember {
+		addr, err := types.ParseAddrPort(fmt.Sprintf("10.0.0.%d:9000", i))
+		t.Require().NoError(err)
+
+		return types.ClusterMember{
+			ClusterMemberLocal: types.ClusterMemberLocal{Name: fmt.Sprintf("n%d", i), Address: addr},
+			Role:               role.String(),
+			FailureDomain:      domain,
+		}
+	}
+
+	toMap := func(members ...types.ClusterMember) map[string]types.ClusterMember {
+		out := make(map[string]types.ClusterMember, len(members))
+		for _, m := range members {
+			out[m.Address.String()] = m
+		}
+
+		return out
+	}
+
+	tests := []struct {
+		name          string
+		members       map[string]types.ClusterMember
+		expectPromote string
+		expectDemote  string
+	}{
+		{
+			name: "Balanced voters",
+			members: toMap(
+				member(1, dqliteClient.Voter, "a"),
+				member(2, dqliteClient.Voter, "b"),
+				member(3, dqliteClient.Voter, "c"),
+				member(4, dqliteClient.StandBy, "a"),
+			),
+		},
+		{
+			name: "Two voters in one domain",
+			members: toMap(
+				member(1, dqliteClient.Voter, "a"),
+				member(2, dqliteClient.Voter, "a"),
+				member(3, dqliteClient.Voter, "b"),
+				member(4, dqliteClient.Spare, "c"),
+				member(5, dqliteClient.StandBy, "c"),
+			),
+			expectPromote: "n5",
+			expectDemote:  "n2",
+		},
+		{
+			name: "No candidate in another domain",
+			members: toMap(
+				member(1, dqliteClient.Voter, "a"),
+				member(2, dqliteClient.Voter, "a"),
+				member(3, dqliteClient.Voter, "b"),
+				member(4, dqliteClient.StandBy, "b"),
+			),
+		},
+		{
+			name: "No failure domains",
+			members: toMap(
+				member(1, dqliteClient.Voter, ""),
+				member(2, dqliteClient.Voter, ""),
+				member(3, dqliteClient.Voter, ""),
+				member(4, dqliteClient.StandBy, ""),
+			),
+		},
+		{
+			name: "Too few voters",
+			members: toMap(
+				member(1, dqliteClient.Voter, "a"),
+				member(2, dqliteClient.Voter, "a"),
+				member(3, dqliteClient.StandBy, "b"),
+			),
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		promote, demote := planFailureDomainRebalance(c.members, "10.0.0.1:9000")
+		if c.expectPromote == "" {
+			t.Nil(promote)
+			t.Nil(demote)
+			continue
+		}
+
+		t.Require().NotNil(promote)
+		t.Require().NotNil(demote)
+		t.Equal(c.expectPromote, promote.Name)
+		t.Equal(c.expectDemote, demote.Name)
+	}
+}
+
+// Ensures repeated rebalancing settles on a stable layout, which further heartbeat rounds leave unchanged.
+func (t *heartbeatSuite) Test_planFailureDomainRebalanceStable() {
+	members := map[string]types.ClusterMember{}
+	for i, domain := range []string{"a", "a", "a", "b", "b", "c", "c"} {
+		addr, err := types.ParseAddrPort(fmt.Sprintf("10.0.0.%d:9000", i+1))
+		t.Require().NoError(err)
+
+		role := dqliteClient.Spare
+		if i < 3 {
+			role = dqliteClient.Voter
+		} else if i < 5 {
+			role = dqliteClient.StandBy
+		}
+
+		members[addr.String()] = types.ClusterMember{
+			ClusterMemberLocal: types.ClusterMemberLocal{Name: fmt.Sprintf("n%d", i+1), Address: addr},
+			Role:               role.String(),
+			FailureDomain:      domain,
+		}
+	}
+
+	swaps := 0
+	for {
+		promote, demote := planFailureDomainRebalance(members, "10.0.0.1:9000")
+		if promote == nil {
+			break
+		}
+
+		swaps++
+		t.Require().LessOrEqual(swaps, len(members), "Rebalancing did not settle")
+
+		promote.Role, demote.Role = demote.Role, promote.Role
+		members[promote.Address.String()] = *promote
+		members[demote.Address.String()] = *demote
+	}
+
+	t.Equal(2, swaps)
+
+	domainVoters := map[string]int{}
+	for _, member := range members {
+		if member.Role == dqliteClient.Voter.String() {
+			domainVoters[member.FailureDomain]++
+		}
+	}
+
+	t.Equal(map[string]int{"a": 1, "b": 1, "c": 1}, domainVoters)
+
+	// The settled layout is left alone on later heartbeat rounds.
+	for i := 0; i < 3; i++ {
+		promote, demote := planFailureDomainRebalance(members, "10.0.0.1:9000")
+		t.Nil(promote)
+		t.Nil(demote)
+	}
+}
+
+func (t *heartbeatSuite) Test_heartbeatStatus() {
+	tests := []struct {
+		name         string
+		failures     int
+		suspect      int
+		offline      int
+		expectStatus types.MemberStatus
+	}{
+		{
+			name:         "No failures",
+			failures:     0,
+			suspect:      1,
+			offline:      3,
+			expectStatus: types.MemberOnline,
+		},
+		{
+			name:         "Reached suspect threshold",
+			failures:     1,
+			suspect:      1,
+			offline:      3,
+			expectStatus: types.MemberSuspect,
+		},
+		{
+			name:         "Below offline threshold",
+			failures:     2,
+			suspect:      1,
+			offline:      3,
+			expectStatus: types.MemberSuspect,
+		},
+		{
+			name:         "Reached offline threshold",
+			failures:     3,
+			suspect:      1,
+			offline:      3,
+			expectStatus: types.MemberOffline,
+		},
+		{
+			name:         "Below suspect threshold",
+			failures:     1,
+			suspect:      2,
+			offline:      4,
+			expectStatus: types.MemberOnline,
+		},
+		{
+			name:         "Same suspect and offline threshold",
+			failures:     2,
+			suspect:      2,
+			offline:      2,
+			expectStatus: types.MemberOffline,
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		t.Equal(c.expectStatus, heartbeatStatus(c.failures, c.suspect, c.offline))
+	}
+}
+
+func (t *heartbeatSuite) Test_planAutoEviction() {
+	now := time.Now()
+	member := func(i int, role dqliteClient.NodeRole, status types.MemberStatus, offlineFor time.Duration) types.ClusterMember {
+		addr, err := types.ParseAddrPort(fmt.Sprintf("10.0.0.%d:9000", i))
+		t.Require().NoError(err)
+
+		return types.ClusterMember{
+			ClusterMemberLocal: types.ClusterMemberLocal{Name: fmt.Sprintf("n%d", i), Address: addr},
+			Role:               role.String(),
+			HeartbeatStatus:    status,
+			LastHeartbeat:      now.Add(-offlineFor),
+		}
+	}
+
+	toMap := func(members ...types.ClusterMember) map[string]types.ClusterMember {
+		out := make(map[string]types.ClusterMember, len(members))
+		for _, m := range members {
+			out[m.Address.String()] = m
+		}
+
+		return out
+	}
+
+	tests := []struct {
+		name        string
+		members     map[string]types.ClusterMember
+		evictAfter  time.Duration
+		minVoters   int
+		expectEvict string
+	}{
+		{
+			name: "Evict offline voter",
+			members: toMap(
+				member(1, dqliteClient.Voter, types.MemberOnline, 0),
+				member(2, dqliteClient.Voter, types.MemberOnline, 0),
+				member(3, dqliteClient.Voter, types.MemberOffline, time.Hour),
+			),
+			evictAfter:  time.Minute,
+			minVoters:   2,
+			expectEvict: "n3",
+		},
+		{
+			name: "Eviction disabled",
+			members: toMap(
+				member(1, dqliteClient.Voter, types.MemberOnline, 0),
+				member(2, dqliteClient.Voter, types.MemberOnline, 0),
+				member(3, dqliteClient.Voter, types.MemberOffline, time.Hour),
+			),
+			minVoters: 1,
+		},
+		{
+			name: "Not offline for long enough",
+			members: toMap(
+				member(1, dqliteClient.Voter, types.MemberOnline, 0),
+				member(2, dqliteClient.Voter, types.MemberOnline, 0),
+				member(3, dqliteClient.Voter, types.MemberOffline, time.Minute),
+			),
+			evictAfter: time.Hour,
+			minVoters:  1,
+		},
+		{
+			name: "Suspect member",
+			members: toMap(
+				member(1, dqliteClient.Voter, types.MemberOnline, 0),
+				member(2, dqliteClient.Spare, types.MemberSuspect, time.Hour),
+			),
+			evictAfter: time.Minute,
+			minVoters:  1,
+		},
+		{
+			name: "Too few online voters",
+			members: toMap(
+				member(1, dqliteClient.Voter, types.MemberOnline, 0),
+				member(2, dqliteClient.Voter, types.MemberSuspect, 0),
+				member(3, dqliteClient.Voter, types.MemberOffline, time.Hour),
+			),
+			evictAfter: time.Minute,
+			minVoters:  3,
+		},
+		{
+			name: "Never evict the leader",
+			members: toMap(
+				member(1, dqliteClient.Voter, types.MemberOffline, time.Hour),
+				member(2, dqliteClient.Voter, types.MemberOnline, 0),
+			),
+			evictAfter: time.Minute,
+			minVoters:  1,
+		},
+		{
+			name: "Evict one member at a time",
+			members: toMap(
+				member(1, dqliteClient.Voter, types.MemberOnline, 0),
+				member(2, dqliteClient.Spare, types.MemberOffline, time.Hour),
+				member(3, dqliteClient.StandBy, types.MemberOffline, time.Hour),
+			),
+			evictAfter:  time.Minute,
+			minVoters:   1,
+			expectEvict: "n2",
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		evict := planAutoEviction(c.members, now, c.evictAfter, c.minVoters, "10.0.0.1:9000")
+		if c.expectEvict == "" {
+			t.Nil(evict)
+			continue
+		}
+
+		t.Require().NotNil(evict)
+		t.Equal(c.expectEvict, evict.Name)
+	}
+}
@@ -88,6 +88,65 @@ func hooksPost(s state.State, r *http.Request) response.Response {
 		if err != nil {
 			return response.SmartError(fmt.Errorf("Failed to run hook on %q after daemon received local config update: %w", s.Name(), err))
 		}
+	case internalTypes.OnClusterConfigUpdate:
+		var req types.ClusterConfig
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		err = intState.Hooks.OnClusterConfigUpdate(ctx, s, req)
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed to run hook on %q after cluster config update: %w", s.Name(), err))
+		}
+	case internalTypes.OnMemberRename:
+		var req internalTypes.HookRenameMemberOptions
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		if req.OldName == "" || req.NewName == "" {
+			return response.SmartError(fmt.Errorf("No member names given for OnMemberRename hook execution"))
+		}
+
+		err = intState.Hooks.OnMemberRename(ctx, s, req.OldName, req.NewName)
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed to run hook on %q after cluster member %q was renamed to %q: %w", s.Name(), req.OldName, req.NewName, err))
+		}
+	case internalTypes.OnMemberDown:
+		var req internalTypes.HookMemberStatusOptions
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		err = intState.Hooks.OnMemberDown(ctx, s, req.Member)
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed to run hook on %q after cluster member %q became %s: %w", s.Name(), req.Member.Name, req.Member.HeartbeatStatus, err))
+		}
+	case internalTypes.OnMemberUp:
+		var req internalTypes.HookMemberStatusOptions
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		err = intState.Hooks.OnMemberUp(ctx, s, req.Member)
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed to run hook on %q after cluster member %q came back online: %w", s.Name(), req.Member.Name, err))
+		}
+	case internalTypes.OnMemberEvicted:
+		var req internalTypes.HookMemberStatusOptions
+		err = json.NewDecoder(r.Body).Decode(&req)
+		if err != nil {
+			return response.BadRequest(err)
+		}
+
+		err = intState.Hooks.OnMemberEvicted(ctx, s, req.Member)
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed to run hook on %q after cluster member %q was evicted: %w", s.Name(), req.Member.Name, err))
+		}
 	default:
 		return response.SmartError(fmt.Errorf("No valid hook found for the given type"))
 	}
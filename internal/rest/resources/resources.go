@@ -29,6 +29,17 @@ var PublicEndpoints = rest.Resources{
 		clusterCertificatesCmd,
 		clusterCmd,
 		clusterMemberCmd,
+		clusterMemberRoleCmd,
+		clusterMemberHealthCmd,
+		clusterMemberAddressCmd,
+		clusterConfigCmd,
+		upgradeCmd,
+		databaseInfoCmd,
+		databaseSchemaCmd,
+		databaseSchemaDryRunCmd,
+		databaseBackupCmd,
+		databaseBackupsCmd,
+		databaseChangesCmd,
 		daemonCmd,
 		tokenCmd,
 		readyCmd,
@@ -42,6 +53,7 @@ var InternalEndpoints = rest.Resources{
 		clusterInternalCmd,
 		clusterMemberInternalCmd,
 		databaseCmd,
+		databaseChangesInternalCmd,
 		sqlCmd,
 		heartbeatCmd,
 		trustCmd,
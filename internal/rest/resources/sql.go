@@ -5,15 +5,25 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
 	"github.com/canonical/lxd/lxd/db/query"
+	"github.com/canonical/lxd/lxd/request"
 	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/lxd/ucred"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
 	"github.com/canonical/lxd/shared/logger"
 
+	"github.com/canonical/microcluster/v3/internal/db"
+	"github.com/canonical/microcluster/v3/internal/db/update"
 	"github.com/canonical/microcluster/v3/internal/rest/types"
 	"github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/rest"
@@ -54,12 +64,20 @@ func sqlGet(state state.State, r *http.Request) response.Response {
 }
 
 // Execute queries.
-func sqlPost(state state.State, r *http.Request) response.Response {
+func sqlPost(s state.State, r *http.Request) response.Response {
 	parentCtx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
+
+	intState, err := state.ToInternal(s)
+	if err != nil {
+		return response.SmartError(err)
+	}
+
 	req := &types.SQLQuery{}
-	// Parse the request.
-	err := json.NewDecoder(r.Body).Decode(&req)
+	// Parse the request. Decode numbers as json.Number so that integer arguments keep their precision.
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	err = decoder.Decode(&req)
 	if err != nil {
 		return response.BadRequest(err)
 	}
@@ -68,38 +86,541 @@ func sqlPost(state state.State, r *http.Request) response.Response {
 		return response.BadRequest(fmt.Errorf("No query provided"))
 	}
 
-	// TODO: Handle .sync query.
+	var statements []sqlStatement
+	meta := strings.HasPrefix(strings.TrimSpace(req.Query), ".")
+	if meta {
+		if len(req.Args) > 0 {
+			return response.BadRequest(fmt.Errorf("Query arguments cannot be used with meta-commands"))
+		}
 
-	batch := types.SQLBatch{}
-	for _, query := range strings.Split(req.Query, ";") {
-		query = strings.TrimLeft(query, " ")
-		if query == "" {
-			continue
+		statements = []sqlStatement{{text: strings.TrimSpace(req.Query), meta: true}}
+	} else {
+		statements, err = splitSQLStatements(req.Query)
+		if err != nil {
+			return response.BadRequest(err)
 		}
+	}
+
+	if len(statements) == 0 {
+		return response.BadRequest(fmt.Errorf("No query provided"))
+	}
 
+	if len(req.Args) > 0 && len(statements) > 1 {
+		return response.BadRequest(fmt.Errorf("Query arguments can only be used with a single statement"))
+	}
+
+	// Requests from other systems can only read from the database if writes are restricted to the unix socket.
+	// Meta-commands never modify the database.
+	remote := r.RemoteAddr != "@"
+	forceReadOnly := intState.SQLWritesUnixOnly && remote
+	readOnly := req.ReadOnly || forceReadOnly || meta
+	if readOnly {
+		err = validateReadOnlySQL(statements, forceReadOnly)
+		if err != nil {
+			return response.SmartError(err)
+		}
+	}
+
+	args := make([]any, 0, len(req.Args))
+	for _, arg := range req.Args {
+		args = append(args, sqlArg(arg))
+	}
+
+	batch := types.SQLBatch{}
+	runStatement := func(ctx context.Context, tx *sql.Tx, statement sqlStatement) error {
+		var err error
 		result := types.SQLResult{}
-		err = state.Database().Transaction(parentCtx, func(ctx context.Context, tx *sql.Tx) error {
-			if strings.HasPrefix(strings.ToUpper(query), "SELECT") {
-				err = sqlSelect(ctx, tx, query, &result)
-			} else {
-				err = sqlExec(ctx, tx, query, &result)
+		if statement.meta {
+			query, metaArgs, metaErr := sqlMetaQuery(statement.text)
+			if metaErr != nil {
+				return metaErr
 			}
 
+			err = sqlSelect(ctx, tx, query, &result, metaArgs...)
+		} else if statement.isQuery() {
+			err = sqlSelect(ctx, tx, statement.text, &result, args...)
+		} else {
+			err = sqlExec(ctx, tx, statement.text, &result, args...)
+		}
+
+		if err != nil {
 			return err
+		}
+
+		batch.Results = append(batch.Results, result)
+
+		return nil
+	}
+
+	// Record every statement run by other systems, and those run over the unix socket which may have modified the
+	// database, along with the outcome of their transaction.
+	audit := func(statements []sqlStatement, err error) {
+		if readOnly && !remote {
+			return
+		}
+
+		entries := make([]sqlAuditEntry, 0, len(statements))
+		for _, statement := range statements {
+			if !remote && statement.isReadOnly() {
+				continue
+			}
+
+			entry := newSQLAuditEntry(s, r, statement.text, args)
+			if err != nil {
+				entry.Error = err.Error()
+			}
+
+			entries = append(entries, entry)
+		}
+
+		err = writeSQLAuditLog(s.FileSystem().SQLAuditLog, entries)
+		if err != nil {
+			logger.Error("Failed to write to SQL audit log", logger.Ctx{"error": err})
+		}
+	}
+
+	if req.Atomic {
+		err = sqlTransaction(parentCtx, s.Database(), readOnly, func(ctx context.Context, tx *sql.Tx) error {
+			for _, statement := range statements {
+				err := runStatement(ctx, tx, statement)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
 		})
+		audit(statements, err)
 		if err != nil {
 			return response.SmartError(err)
 		}
 
-		batch.Results = append(batch.Results, result)
+		return response.SyncResponse(true, batch)
+	}
+
+	for _, statement := range statements {
+		err = sqlTransaction(parentCtx, s.Database(), readOnly, func(ctx context.Context, tx *sql.Tx) error {
+			return runStatement(ctx, tx, statement)
+		})
+		audit([]sqlStatement{statement}, err)
+		if err != nil {
+			return response.SmartError(err)
+		}
 	}
 
 	return response.SyncResponse(true, batch)
 }
 
-func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult) error {
+// sqlTransaction runs the given function in a database transaction, which cannot modify the database if readOnly is true.
+func sqlTransaction(ctx context.Context, db db.DB, readOnly bool, f func(context.Context, *sql.Tx) error) error {
+	if readOnly {
+		return db.ReadTransaction(ctx, f)
+	}
+
+	return db.Transaction(ctx, f)
+}
+
+// sqlAuditEntry is a record in the SQL audit log of a statement run by another system, or which may have modified the
+// database.
+type sqlAuditEntry struct {
+	Time        time.Time `json:"time"`
+	Protocol    string    `json:"protocol"`
+	Address     string    `json:"address,omitempty"`
+	Fingerprint string    `json:"fingerprint,omitempty"`
+	Member      string    `json:"member,omitempty"`
+	UID         *uint32   `json:"uid,omitempty"`
+	GID         *uint32   `json:"gid,omitempty"`
+	PID         *int32    `json:"pid,omitempty"`
+	Statement   string    `json:"statement"`
+	Args        []any     `json:"args,omitempty"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// newSQLAuditEntry returns an audit log entry for the given statement, identifying the caller of the request.
+func newSQLAuditEntry(s state.State, r *http.Request, statement string, args []any) sqlAuditEntry {
+	entry := sqlAuditEntry{
+		Time:      time.Now().UTC(),
+		Statement: statement,
+		Args:      args,
+	}
+
+	if r.RemoteAddr == "@" {
+		entry.Protocol = "unix"
+
+		// Identify the local process behind the request by the credentials of the socket peer.
+		_, ok := r.Context().Value(request.CtxConn).(net.Conn)
+		if ok {
+			cred, err := ucred.GetCredFromContext(r.Context())
+			if err != nil {
+				logger.Warn("Failed to get the credentials of the unix socket peer", logger.Ctx{"error": err})
+			} else {
+				entry.UID = &cred.Uid
+				entry.GID = &cred.Gid
+				entry.PID = &cred.Pid
+			}
+		}
+
+		return entry
+	}
+
+	entry.Protocol = "tls"
+	entry.Address = r.RemoteAddr
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		entry.Fingerprint = shared.CertFingerprint(r.TLS.PeerCertificates[0])
+
+		remote := s.Remotes().RemoteByCertificateFingerprint(entry.Fingerprint)
+		if remote != nil {
+			entry.Member = remote.Name
+		}
+	}
+
+	return entry
+}
+
+// sqlAuditMu serializes writes to the SQL audit log.
+var sqlAuditMu sync.Mutex
+
+// writeSQLAuditLog appends the given entries to the SQL audit log at the given path, one JSON object per line.
+func writeSQLAuditLog(path string, entries []sqlAuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	sqlAuditMu.Lock()
+	defer sqlAuditMu.Unlock()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to open SQL audit log: %w", err)
+	}
+
+	encoder := json.NewEncoder(f)
+	for _, entry := range entries {
+		err = encoder.Encode(entry)
+		if err != nil {
+			_ = f.Close()
+
+			return fmt.Errorf("Failed to write SQL audit log entry: %w", err)
+		}
+	}
+
+	return f.Close()
+}
+
+// sqlMetaQuery returns the query, and its arguments, implementing the given read-only meta-command.
+func sqlMetaQuery(command string) (query string, args []any, err error) {
+	name, arg, _ := strings.Cut(command, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch name {
+	case ".tables":
+		if arg != "" {
+			return "", nil, api.StatusErrorf(http.StatusBadRequest, "Meta-command %q takes no arguments", name)
+		}
+
+		return "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name", nil, nil
+	case ".indexes":
+		query = "SELECT name, tbl_name AS 'table' FROM sqlite_master WHERE type = 'index' AND name NOT LIKE 'sqlite_%'"
+		if arg != "" {
+			query += " AND tbl_name = ?"
+			args = append(args, arg)
+		}
+
+		return query + " ORDER BY tbl_name, name", args, nil
+	case ".count":
+		if arg == "" {
+			return "", nil, api.StatusErrorf(http.StatusBadRequest, "Meta-command %q requires a table name", name)
+		}
+
+		return fmt.Sprintf("SELECT COUNT(*) AS count FROM %s", update.QuoteIdentifier(arg)), nil, nil
+	case ".explain":
+		statements, err := splitSQLStatements(arg)
+		if err != nil {
+			return "", nil, api.StatusErrorf(http.StatusBadRequest, "Invalid query for meta-command %q: %w", name, err)
+		}
+
+		if len(statements) != 1 {
+			return "", nil, api.StatusErrorf(http.StatusBadRequest, "Meta-command %q requires a single query", name)
+		}
+
+		return "EXPLAIN QUERY PLAN " + statements[0].text, nil, nil
+	case ".sync":
+		// TODO: Wait for the local member to apply all raft entries, once dqlite exposes its applied index.
+		return "", nil, api.StatusErrorf(http.StatusNotImplemented, "Meta-command %q is not supported, as dqlite does not expose the raft index applied by the local member", name)
+	case ".dump", ".schema":
+		return "", nil, api.StatusErrorf(http.StatusBadRequest, "Meta-command %q is only supported by GET requests", name)
+	}
+
+	return "", nil, api.StatusErrorf(http.StatusBadRequest, "Unknown meta-command %q", name)
+}
+
+// sqlArg converts a query argument decoded from JSON into a value suitable for the database driver.
+// Numbers are passed as integers where possible, and as floats otherwise.
+func sqlArg(arg any) any {
+	number, ok := arg.(json.Number)
+	if !ok {
+		return arg
+	}
+
+	intValue, err := number.Int64()
+	if err == nil {
+		return intValue
+	}
+
+	floatValue, err := number.Float64()
+	if err == nil {
+		return floatValue
+	}
+
+	return number.String()
+}
+
+// sqlStatement is a single SQL statement, along with its leading keyword.
+type sqlStatement struct {
+	text    string
+	keyword string
+	tokens  []string // Leading tokens of the statement, upper-cased and with identifiers and strings unquoted.
+	meta    bool     // Whether the statement is a meta-command, such as ".tables", which never modifies the database.
+}
+
+// unsafeReadOnlyPragmas are the pragmas which could lift the read-only mode of a transaction.
+var unsafeReadOnlyPragmas = map[string]bool{
+	"QUERY_ONLY":      true,
+	"WRITABLE_SCHEMA": true,
+}
+
+// isQuery returns whether the statement returns rows, rather than only modifying the database.
+func (s sqlStatement) isQuery() bool {
+	if s.meta {
+		return true
+	}
+
+	switch s.keyword {
+	case "SELECT", "WITH", "EXPLAIN", "VALUES", "PRAGMA":
+		return true
+	}
+
+	return false
+}
+
+// isReadOnly returns whether the statement can never modify the database.
+// Queries starting with WITH or PRAGMA are not considered read-only, as they can also make changes.
+func (s sqlStatement) isReadOnly() bool {
+	if s.meta {
+		return true
+	}
+
+	switch s.keyword {
+	case "SELECT", "EXPLAIN", "VALUES":
+		return true
+	}
+
+	return false
+}
+
+// pragmaName returns the upper-cased name of the pragma of a PRAGMA statement, without its schema name.
+func (s sqlStatement) pragmaName() string {
+	if s.keyword != "PRAGMA" || len(s.tokens) < 2 {
+		return ""
+	}
+
+	if len(s.tokens) >= 4 && s.tokens[2] == "." {
+		return s.tokens[3]
+	}
+
+	return s.tokens[1]
+}
+
+// validateReadOnlySQL checks that the given statements can be run in a read-only transaction, by rejecting the pragmas
+// that could lift the read-only mode of the transaction. If strict is true, only statements that can never modify the
+// database are allowed at all.
+func validateReadOnlySQL(statements []sqlStatement, strict bool) error {
+	for _, statement := range statements {
+		if strict && !statement.isReadOnly() {
+			return api.StatusErrorf(http.StatusForbidden, "SQL statements that modify the database can only be run over the unix socket")
+		}
+
+		if unsafeReadOnlyPragmas[statement.pragmaName()] {
+			return api.StatusErrorf(http.StatusBadRequest, "Pragma %q cannot be used in read-only mode", strings.ToLower(statement.pragmaName()))
+		}
+	}
+
+	return nil
+}
+
+// splitSQLStatements splits the given text into its SQL statements, dropping the terminating semicolons.
+// Semicolons inside string literals, quoted identifiers, comments, and the body of a CREATE TRIGGER statement do not
+// end a statement. Statements consisting only of whitespace and comments are skipped.
+func splitSQLStatements(text string) ([]sqlStatement, error) {
+	statements := []sqlStatement{}
+
+	var current strings.Builder
+	var keywords []string
+	var tokens []string
+	lastKeyword := ""
+	inTrigger := false
+	caseDepth := 0 // Number of CASE expressions open within a trigger body, which also end with the END keyword.
+
+	flush := func() {
+		if len(keywords) > 0 {
+			statements = append(statements, sqlStatement{text: strings.TrimSpace(current.String()), keyword: keywords[0], tokens: tokens})
+		}
+
+		current.Reset()
+		keywords = nil
+		tokens = nil
+		lastKeyword = ""
+		inTrigger = false
+		caseDepth = 0
+	}
+
+	addToken := func(token string) {
+		if len(tokens) < 4 {
+			tokens = append(tokens, strings.ToUpper(token))
+		}
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case r == '-' && next == '-':
+			end := i
+			for end < len(runes) && runes[end] != '\n' {
+				end++
+			}
+
+			current.WriteString(string(runes[i:end]))
+			i = end - 1
+		case r == '/' && next == '*':
+			end := i + 2
+			for end+1 < len(runes) && (runes[end] != '*' || runes[end+1] != '/') {
+				end++
+			}
+
+			if end+1 >= len(runes) {
+				return nil, fmt.Errorf("Unterminated comment in SQL query")
+			}
+
+			current.WriteString(string(runes[i : end+2]))
+			i = end + 1
+		case r == '\'' || r == '"' || r == '`' || r == '[':
+			closing := r
+			if r == '[' {
+				closing = ']'
+			}
+
+			end := i + 1
+			for ; end < len(runes); end++ {
+				if runes[end] != closing {
+					continue
+				}
+
+				// Quotes are escaped by doubling them.
+				if closing != ']' && end+1 < len(runes) && runes[end+1] == closing {
+					end++
+					continue
+				}
+
+				break
+			}
+
+			if end >= len(runes) {
+				return nil, fmt.Errorf("Unterminated %q in SQL query", string(r))
+			}
+
+			current.WriteString(string(runes[i : end+1]))
+			unquoted := string(runes[i+1 : end])
+			if closing != ']' {
+				unquoted = strings.ReplaceAll(unquoted, string(closing)+string(closing), string(closing))
+			}
+
+			addToken(unquoted)
+			i = end
+
+			// A quoted token can start a statement, but is never a keyword.
+			if len(keywords) == 0 {
+				keywords = append(keywords, "")
+			}
+
+			lastKeyword = ""
+		case r == ';':
+			// Trigger bodies contain statements of their own, and only end with the END keyword.
+			if inTrigger && lastKeyword != "END" {
+				current.WriteRune(r)
+				lastKeyword = ""
+				continue
+			}
+
+			flush()
+		case r == '_' || unicode.IsLetter(r):
+			end := i + 1
+			for end < len(runes) && (runes[end] == '_' || runes[end] == '$' || unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+				end++
+			}
+
+			word := string(runes[i:end])
+			current.WriteString(word)
+			addToken(word)
+			i = end - 1
+
+			lastKeyword = strings.ToUpper(word)
+			if len(keywords) < 3 {
+				keywords = append(keywords, lastKeyword)
+				inTrigger = isCreateTrigger(keywords)
+			}
+
+			if inTrigger {
+				switch lastKeyword {
+				case "CASE":
+					caseDepth++
+				case "END":
+					if caseDepth > 0 {
+						// This END closes a CASE expression rather than the trigger body.
+						caseDepth--
+						lastKeyword = ""
+					}
+				}
+			}
+		default:
+			current.WriteRune(r)
+			if !unicode.IsSpace(r) {
+				addToken(string(r))
+				if len(keywords) == 0 {
+					keywords = append(keywords, "")
+				}
+
+				lastKeyword = ""
+			}
+		}
+	}
+
+	flush()
+
+	return statements, nil
+}
+
+// isCreateTrigger returns whether the leading keywords of a statement are those of a CREATE TRIGGER statement.
+func isCreateTrigger(keywords []string) bool {
+	if len(keywords) < 2 || keywords[0] != "CREATE" {
+		return false
+	}
+
+	if keywords[1] == "TRIGGER" {
+		return true
+	}
+
+	return len(keywords) == 3 && (keywords[1] == "TEMP" || keywords[1] == "TEMPORARY") && keywords[2] == "TRIGGER"
+}
+
+func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult, args ...any) error {
 	result.Type = "select"
-	rows, err := tx.QueryContext(ctx, query)
+	rows, err := tx.QueryContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("Failed to execute query: %w", err)
 	}
@@ -148,9 +669,9 @@ func sqlSelect(ctx context.Context, tx *sql.Tx, query string, result *types.SQLR
 	return nil
 }
 
-func sqlExec(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult) error {
+func sqlExec(ctx context.Context, tx *sql.Tx, query string, result *types.SQLResult, args ...any) error {
 	result.Type = "exec"
-	r, err := tx.ExecContext(ctx, query)
+	r, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("Failed to exec query: %w", err)
 	}
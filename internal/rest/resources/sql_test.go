@@ -0,0 +1,399 @@
+package resources
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/canonical/lxd/lxd/request"
+	"github.com/stretchr/testify/suite"
+)
+
+type sqlSuite struct {
+	suite.Suite
+}
+
+func TestSQLSuite(t *testing.T) {
+	suite.Run(t, new(sqlSuite))
+}
+
+func (t *sqlSuite) Test_splitSQLStatements() {
+	tests := []struct {
+		name          string
+		query         string
+		expectTexts   []string
+		expectQueries []bool
+		expectErr     bool
+	}{
+		{
+			name:          "Single statement without semicolon",
+			query:         "SELECT * FROM test",
+			expectTexts:   []string{"SELECT * FROM test"},
+			expectQueries: []bool{true},
+		},
+		{
+			name:          "Multiple statements",
+			query:         "INSERT INTO test VALUES (1); select * from test;  ;\n DELETE FROM test",
+			expectTexts:   []string{"INSERT INTO test VALUES (1)", "select * from test", "DELETE FROM test"},
+			expectQueries: []bool{false, true, false},
+		},
+		{
+			name:          "Semicolons in literals and identifiers",
+			query:         `INSERT INTO "a;b" VALUES ('it''s; here', [c;d], ` + "`e;f`" + `); SELECT 1`,
+			expectTexts:   []string{`INSERT INTO "a;b" VALUES ('it''s; here', [c;d], ` + "`e;f`" + `)`, "SELECT 1"},
+			expectQueries: []bool{false, true},
+		},
+		{
+			name:          "Semicolons in comments",
+			query:         "SELECT 1 -- one; two\n; /* three; */ SELECT 2; -- trailing comment",
+			expectTexts:   []string{"SELECT 1 -- one; two", "/* three; */ SELECT 2"},
+			expectQueries: []bool{true, true},
+		},
+		{
+			name:          "Queries not starting with SELECT",
+			query:         "WITH x AS (SELECT 1) SELECT * FROM x; EXPLAIN QUERY PLAN SELECT 1; VALUES (1); PRAGMA table_info('test')",
+			expectTexts:   []string{"WITH x AS (SELECT 1) SELECT * FROM x", "EXPLAIN QUERY PLAN SELECT 1", "VALUES (1)", "PRAGMA table_info('test')"},
+			expectQueries: []bool{true, true, true, true},
+		},
+		{
+			name:          "Trigger body",
+			query:         "CREATE TEMP TRIGGER t AFTER INSERT ON test BEGIN UPDATE test SET x = 1; DELETE FROM other; END; SELECT 1",
+			expectTexts:   []string{"CREATE TEMP TRIGGER t AFTER INSERT ON test BEGIN UPDATE test SET x = 1; DELETE FROM other; END", "SELECT 1"},
+			expectQueries: []bool{false, true},
+		},
+		{
+			name:          "CASE expression in a trigger body",
+			query:         "CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE a SET x = CASE WHEN 1 THEN 2 END; DELETE FROM b; END",
+			expectTexts:   []string{"CREATE TRIGGER t AFTER INSERT ON a BEGIN UPDATE a SET x = CASE WHEN 1 THEN 2 END; DELETE FROM b; END"},
+			expectQueries: []bool{false},
+		},
+		{
+			name:          "Nested CASE expressions in a trigger body",
+			query:         "CREATE TRIGGER t AFTER INSERT ON a BEGIN SELECT CASE WHEN 1 THEN CASE x WHEN 1 THEN 2 END END; END; SELECT 1",
+			expectTexts:   []string{"CREATE TRIGGER t AFTER INSERT ON a BEGIN SELECT CASE WHEN 1 THEN CASE x WHEN 1 THEN 2 END END; END", "SELECT 1"},
+			expectQueries: []bool{false, true},
+		},
+		{
+			name:          "Table named like a trigger keyword",
+			query:         "CREATE TABLE trigger (end TEXT); SELECT 1",
+			expectTexts:   []string{"CREATE TABLE trigger (end TEXT)", "SELECT 1"},
+			expectQueries: []bool{false, true},
+		},
+		{
+			name:          "Only comments",
+			query:         "-- nothing here\n/* or here */",
+			expectTexts:   []string{},
+			expectQueries: []bool{},
+		},
+		{
+			name:      "Unterminated string",
+			query:     "SELECT 'abc; SELECT 1",
+			expectErr: true,
+		},
+		{
+			name:      "Unterminated comment",
+			query:     "SELECT 1 /* abc",
+			expectErr: true,
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		statements, err := splitSQLStatements(c.query)
+		if c.expectErr {
+			t.Error(err)
+			continue
+		}
+
+		t.NoError(err)
+
+		texts := make([]string, 0, len(statements))
+		queries := make([]bool, 0, len(statements))
+		for _, statement := range statements {
+			texts = append(texts, statement.text)
+			queries = append(queries, statement.isQuery())
+		}
+
+		t.Equal(c.expectTexts, texts)
+		t.Equal(c.expectQueries, queries)
+	}
+}
+
+func (t *sqlSuite) Test_sqlArg() {
+	tests := []struct {
+		name   string
+		arg    any
+		expect any
+	}{
+		{
+			name:   "Integer",
+			arg:    json.Number("9007199254740993"),
+			expect: int64(9007199254740993),
+		},
+		{
+			name:   "Float",
+			arg:    json.Number("1.5"),
+			expect: 1.5,
+		},
+		{
+			name:   "String",
+			arg:    "abc",
+			expect: "abc",
+		},
+		{
+			name:   "Null",
+			arg:    nil,
+			expect: nil,
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		t.Equal(c.expect, sqlArg(c.arg))
+	}
+}
+
+func (t *sqlSuite) Test_sqlMetaQuery() {
+	tests := []struct {
+		name        string
+		command     string
+		expectQuery string
+		expectArgs  []any
+		expectErr   bool
+	}{
+		{
+			name:        "List tables",
+			command:     ".tables",
+			expectQuery: "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name",
+		},
+		{
+			name:        "List all indexes",
+			command:     ".indexes",
+			expectQuery: "SELECT name, tbl_name AS 'table' FROM sqlite_master WHERE type = 'index' AND name NOT LIKE 'sqlite_%' ORDER BY tbl_name, name",
+		},
+		{
+			name:        "List indexes of a table",
+			command:     ".indexes  core_cluster_members",
+			expectQuery: "SELECT name, tbl_name AS 'table' FROM sqlite_master WHERE type = 'index' AND name NOT LIKE 'sqlite_%' AND tbl_name = ? ORDER BY tbl_name, name",
+			expectArgs:  []any{"core_cluster_members"},
+		},
+		{
+			name:        "Count rows with a quoted table name",
+			command:     `.count my"table`,
+			expectQuery: `SELECT COUNT(*) AS count FROM "my""table"`,
+		},
+		{
+			name:        "Explain a query",
+			command:     ".explain SELECT * FROM core_cluster_members WHERE name = 'a;b';",
+			expectQuery: "EXPLAIN QUERY PLAN SELECT * FROM core_cluster_members WHERE name = 'a;b'",
+		},
+		{
+			name:      "Explain multiple queries",
+			command:   ".explain SELECT 1; SELECT 2",
+			expectErr: true,
+		},
+		{
+			name:      "Count without a table",
+			command:   ".count",
+			expectErr: true,
+		},
+		{
+			name:      "Tables with an argument",
+			command:   ".tables abc",
+			expectErr: true,
+		},
+		{
+			name:      "Dump is only supported by GET",
+			command:   ".dump",
+			expectErr: true,
+		},
+		{
+			name:      "Sync is not supported",
+			command:   ".sync",
+			expectErr: true,
+		},
+		{
+			name:      "Unknown meta-command",
+			command:   ".quit",
+			expectErr: true,
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		query, args, err := sqlMetaQuery(c.command)
+		if c.expectErr {
+			t.Error(err)
+			continue
+		}
+
+		t.NoError(err)
+		t.Equal(c.expectQuery, query)
+		t.Equal(c.expectArgs, args)
+	}
+}
+
+func (t *sqlSuite) Test_validateReadOnlySQL() {
+	tests := []struct {
+		name      string
+		query     string
+		strict    bool
+		expectErr bool
+	}{
+		{
+			name:  "Queries",
+			query: "SELECT 1; WITH x AS (SELECT 1) SELECT * FROM x; PRAGMA table_info('query_only')",
+		},
+		{
+			name:   "Queries which can never modify the database",
+			query:  "SELECT 1; VALUES (1); EXPLAIN DELETE FROM test",
+			strict: true,
+		},
+		{
+			name:      "Lifting the read-only mode before a write",
+			query:     "PRAGMA query_only = OFF; WITH x AS (SELECT 1) DELETE FROM core_token_records",
+			expectErr: true,
+		},
+		{
+			name:      "Lifting the read-only mode when restricted to queries",
+			query:     "PRAGMA query_only = OFF; WITH x AS (SELECT 1) DELETE FROM core_token_records",
+			strict:    true,
+			expectErr: true,
+		},
+		{
+			name:      "Quoted pragma with a schema name",
+			query:     "/* comment */ pragma main.'Query_Only' = 0",
+			expectErr: true,
+		},
+		{
+			name:      "Writable schema",
+			query:     `PRAGMA "writable_schema" = ON`,
+			expectErr: true,
+		},
+		{
+			name:      "Common table expression when restricted to queries",
+			query:     "WITH x AS (SELECT 1) SELECT * FROM x",
+			strict:    true,
+			expectErr: true,
+		},
+		{
+			name:      "Pragma when restricted to queries",
+			query:     "PRAGMA table_info('test')",
+			strict:    true,
+			expectErr: true,
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		statements, err := splitSQLStatements(c.query)
+		t.Require().NoError(err)
+
+		err = validateReadOnlySQL(statements, c.strict)
+		if c.expectErr {
+			t.Error(err)
+		} else {
+			t.NoError(err)
+		}
+	}
+}
+
+func (t *sqlSuite) Test_writeSQLAuditLog() {
+	path := filepath.Join(t.T().TempDir(), "sql_audit.log")
+
+	tests := []struct {
+		name    string
+		entries []sqlAuditEntry
+	}{
+		{
+			name:    "No entries",
+			entries: []sqlAuditEntry{},
+		},
+		{
+			name: "Statement over the unix socket",
+			entries: []sqlAuditEntry{
+				{Time: time.Now().UTC(), Protocol: "unix", Statement: "DELETE FROM test"},
+			},
+		},
+		{
+			name: "Failed statements from a cluster member",
+			entries: []sqlAuditEntry{
+				{Time: time.Now().UTC(), Protocol: "tls", Address: "10.0.0.1:9000", Fingerprint: "abc", Member: "n1", Statement: "INSERT INTO test VALUES (?)", Args: []any{"a"}, Error: "Failed"},
+				{Time: time.Now().UTC(), Protocol: "tls", Address: "10.0.0.1:9000", Fingerprint: "abc", Member: "n1", Statement: "DROP TABLE test", Error: "Failed"},
+			},
+		},
+	}
+
+	expected := []sqlAuditEntry{}
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		err := writeSQLAuditLog(path, c.entries)
+		t.NoError(err)
+
+		expected = append(expected, c.entries...)
+		if len(expected) == 0 {
+			t.NoFileExists(path)
+			continue
+		}
+
+		f, err := os.Open(path)
+		t.Require().NoError(err)
+
+		entries := []sqlAuditEntry{}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			entry := sqlAuditEntry{}
+			t.NoError(json.Unmarshal(scanner.Bytes(), &entry))
+			entries = append(entries, entry)
+		}
+
+		t.NoError(scanner.Err())
+		t.NoError(f.Close())
+		t.Equal(expected, entries)
+	}
+
+	info, err := os.Stat(path)
+	t.Require().NoError(err)
+	t.Equal(os.FileMode(0600), info.Mode().Perm())
+}
+
+func (t *sqlSuite) Test_newSQLAuditEntry() {
+	listener, err := net.ListenUnix("unix", &net.UnixAddr{Name: filepath.Join(t.T().TempDir(), "control.socket"), Net: "unix"})
+	t.Require().NoError(err)
+	defer func() { _ = listener.Close() }()
+
+	client, err := net.Dial("unix", listener.Addr().String())
+	t.Require().NoError(err)
+	defer func() { _ = client.Close() }()
+
+	conn, err := listener.AcceptUnix()
+	t.Require().NoError(err)
+	defer func() { _ = conn.Close() }()
+
+	// Requests over the unix socket record the credentials of the process on the other end.
+	r := httptest.NewRequest(http.MethodPost, "/core/internal/sql", nil)
+	r.RemoteAddr = "@"
+	r = r.WithContext(context.WithValue(r.Context(), request.CtxConn, net.Conn(conn)))
+
+	entry := newSQLAuditEntry(nil, r, ".tables", nil)
+	t.Equal("unix", entry.Protocol)
+	t.Equal(".tables", entry.Statement)
+	t.Require().NotNil(entry.UID)
+	t.Require().NotNil(entry.GID)
+	t.Require().NotNil(entry.PID)
+	t.Equal(uint32(os.Getuid()), *entry.UID)
+	t.Equal(uint32(os.Getgid()), *entry.GID)
+	t.Equal(int32(os.Getpid()), *entry.PID)
+}
@@ -125,7 +125,7 @@ func tokensGet(state state.State, r *http.Request) response.Response {
 	}
 
 	var records []internalTypes.TokenRecord
-	err = state.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+	err = state.Database().ReadTransaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
 		var err error
 		tokens, err := cluster.GetCoreTokenRecords(ctx, tx)
 		if err != nil {
@@ -72,12 +72,38 @@ func trustPost(s state.State, r *http.Request) response.Response {
 
 	// At this point, the node has joined dqlite so we can add a local record for it if we haven't already from a heartbeat (or if we are the leader).
 	remotes := s.Remotes()
-	_, ok := remotes.RemotesByName()[newRemote.Name]
+	remotesMap := remotes.RemotesByName()
+	existingRemote, ok := remotesMap[newRemote.Name]
 	if !ok {
 		err = remotes.Add(s.FileSystem().TrustDir, newRemote)
 		if err != nil {
 			return response.SmartError(fmt.Errorf("Failed adding local record of newly joined node %q: %w", req.Name, err))
 		}
+
+		return response.EmptySyncResponse
+	}
+
+	// If the cluster member's address has changed, replace its existing record.
+	if existingRemote.Address != newRemote.Address {
+		remotesMap[newRemote.Name] = newRemote
+
+		newRemotes := make([]types.ClusterMember, 0, len(remotesMap))
+		for _, remote := range remotesMap {
+			newRemote := types.ClusterMember{
+				ClusterMemberLocal: types.ClusterMemberLocal{
+					Name:        remote.Name,
+					Address:     remote.Address,
+					Certificate: remote.Certificate,
+				},
+			}
+
+			newRemotes = append(newRemotes, newRemote)
+		}
+
+		err = remotes.Replace(s.FileSystem().TrustDir, newRemotes...)
+		if err != nil {
+			return response.SmartError(fmt.Errorf("Failed to update local record of node %q: %w", req.Name, err))
+		}
 	}
 
 	return response.EmptySyncResponse
@@ -0,0 +1,124 @@
+package resources
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared"
+	"github.com/canonical/lxd/shared/api"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/rest/access"
+	"github.com/canonical/microcluster/v3/rest/types"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+var upgradeCmd = rest.Endpoint{
+	Path: "upgrade",
+
+	Get: rest.EndpointAction{Handler: upgradeGet, AccessHandler: access.AllowAuthenticated},
+}
+
+// upgradeGet reports the upgrade progress of each cluster member, and which cluster members hold back the rest of the cluster.
+func upgradeGet(s state.State, r *http.Request) response.Response {
+	status := s.Database().Status()
+
+	// If the database is not in a ready or waiting state, we can't be sure it's available for use.
+	if status != types.DatabaseReady && status != types.DatabaseWaiting {
+		return response.SmartError(api.StatusErrorf(http.StatusServiceUnavailable, "%s", string(status)))
+	}
+
+	var clusterMembers []types.ClusterMember
+	err := s.Database().Transaction(r.Context(), func(ctx context.Context, tx *sql.Tx) error {
+		// The upgrading variant works regardless of whether this cluster member has caught up with the schema.
+		schemaInternal, schemaExternal, apiExtensions := s.Database().SchemaVersion()
+		dbClusterMembers, _, err := cluster.GetUpgradingClusterMembers(ctx, tx, schemaInternal, schemaExternal, apiExtensions)
+		if err != nil {
+			return err
+		}
+
+		clusterMembers = make([]types.ClusterMember, 0, len(dbClusterMembers))
+		for _, clusterMember := range dbClusterMembers {
+			apiClusterMember, err := clusterMember.ToAPI()
+			if err != nil {
+				return err
+			}
+
+			clusterMembers = append(clusterMembers, *apiClusterMember)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return response.SmartError(fmt.Errorf("Failed to get cluster members: %w", err))
+	}
+
+	return response.SyncResponse(true, clusterUpgradeStatus(clusterMembers))
+}
+
+// clusterUpgradeStatus compares the schema versions and API extensions of the given cluster members against the most
+// recent schema versions, and the union of API extensions, in the cluster. Cluster members that are behind on either are considered to block the cluster from
+// converging. Pending cluster members are ignored, as they have yet to record their versions.
+func clusterUpgradeStatus(clusterMembers []types.ClusterMember) types.ClusterUpgrade {
+	upgrade := types.ClusterUpgrade{Converged: true, Members: []types.ClusterMemberUpgrade{}}
+
+	var targetExtensions []string
+	for _, member := range clusterMembers {
+		if member.Role == string(cluster.Pending) {
+			continue
+		}
+
+		if member.SchemaInternalVersion > upgrade.SchemaInternalVersion {
+			upgrade.SchemaInternalVersion = member.SchemaInternalVersion
+		}
+
+		if member.SchemaExternalVersion > upgrade.SchemaExternalVersion {
+			upgrade.SchemaExternalVersion = member.SchemaExternalVersion
+		}
+
+		// Members may each have extensions the others lack, so the cluster converges on all of them.
+		for _, extension := range member.Extensions {
+			if !shared.ValueInSlice(extension, targetExtensions) {
+				targetExtensions = append(targetExtensions, extension)
+			}
+		}
+	}
+
+	for _, member := range clusterMembers {
+		if member.Role == string(cluster.Pending) {
+			continue
+		}
+
+		missing := []string{}
+		for _, extension := range targetExtensions {
+			if !member.Extensions.HasExtension(extension) {
+				missing = append(missing, extension)
+			}
+		}
+
+		blocking := member.SchemaInternalVersion < upgrade.SchemaInternalVersion || member.SchemaExternalVersion < upgrade.SchemaExternalVersion || len(missing) > 0
+		if blocking {
+			upgrade.Converged = false
+		}
+
+		upgrade.Members = append(upgrade.Members, types.ClusterMemberUpgrade{
+			Name:                  member.Name,
+			Address:               member.Address,
+			Version:               member.Version,
+			SchemaInternalVersion: member.SchemaInternalVersion,
+			SchemaExternalVersion: member.SchemaExternalVersion,
+			MissingExtensions:     missing,
+			UpgradeStatus:         member.UpgradeStatus,
+			Blocking:              blocking,
+		})
+	}
+
+	sort.Slice(upgrade.Members, func(i, j int) bool { return upgrade.Members[i].Name < upgrade.Members[j].Name })
+
+	return upgrade
+}
@@ -0,0 +1,102 @@
+package resources
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+
+	"github.com/canonical/microcluster/v3/cluster"
+	"github.com/canonical/microcluster/v3/internal/extensions"
+	"github.com/canonical/microcluster/v3/rest/types"
+)
+
+type upgradeSuite struct {
+	suite.Suite
+}
+
+func TestUpgradeSuite(t *testing.T) {
+	suite.Run(t, new(upgradeSuite))
+}
+
+func (t *upgradeSuite) Test_clusterUpgradeStatus() {
+	member := func(i int, schemaInternal uint64, schemaExternal uint64, exts ...string) types.ClusterMember {
+		addr, err := types.ParseAddrPort(fmt.Sprintf("10.0.0.%d:9000", i))
+		t.Require().NoError(err)
+
+		return types.ClusterMember{
+			ClusterMemberLocal:    types.ClusterMemberLocal{Name: fmt.Sprintf("n%d", i), Address: addr},
+			Role:                  "voter",
+			SchemaInternalVersion: schemaInternal,
+			SchemaExternalVersion: schemaExternal,
+			Extensions:            extensions.Extensions(exts),
+			Version:               fmt.Sprintf("1.%d", i),
+		}
+	}
+
+	pending := member(4, 0, 0)
+	pending.Role = string(cluster.Pending)
+
+	tests := []struct {
+		name            string
+		members         []types.ClusterMember
+		expectConverged bool
+		expectBlocking  []string
+		expectMissing   map[string][]string
+	}{
+		{
+			name:            "All members up to date",
+			members:         []types.ClusterMember{member(1, 2, 1, "a"), member(2, 2, 1, "a"), pending},
+			expectConverged: true,
+			expectBlocking:  []string{},
+		},
+		{
+			name:            "Member behind on internal schema",
+			members:         []types.ClusterMember{member(1, 2, 1, "a"), member(2, 1, 1, "a")},
+			expectConverged: false,
+			expectBlocking:  []string{"n2"},
+		},
+		{
+			name:            "Member behind on external schema",
+			members:         []types.ClusterMember{member(1, 2, 0, "a"), member(2, 2, 1, "a")},
+			expectConverged: false,
+			expectBlocking:  []string{"n1"},
+		},
+		{
+			name:            "Member missing API extensions",
+			members:         []types.ClusterMember{member(1, 2, 1, "a", "b", "c"), member(2, 2, 1, "a")},
+			expectConverged: false,
+			expectBlocking:  []string{"n2"},
+			expectMissing:   map[string][]string{"n2": {"b", "c"}},
+		},
+		{
+			name:            "Members with as many but different API extensions",
+			members:         []types.ClusterMember{member(1, 2, 1, "a", "b"), member(2, 2, 1, "a", "c")},
+			expectConverged: false,
+			expectBlocking:  []string{"n1", "n2"},
+			expectMissing:   map[string][]string{"n1": {"c"}, "n2": {"b"}},
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		upgrade := clusterUpgradeStatus(c.members)
+		t.Equal(c.expectConverged, upgrade.Converged)
+
+		blocking := []string{}
+		for _, m := range upgrade.Members {
+			t.NotEqual(pending.Name, m.Name)
+			if m.Blocking {
+				blocking = append(blocking, m.Name)
+			}
+
+			if c.expectMissing[m.Name] != nil {
+				t.Equal(c.expectMissing[m.Name], m.MissingExtensions)
+			}
+		}
+
+		t.Equal(c.expectBlocking, blocking)
+		t.Equal("1.1", upgrade.Members[0].Version)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/canonical/microcluster/v3/cluster"
 	internalAccess "github.com/canonical/microcluster/v3/internal/rest/access"
 	"github.com/canonical/microcluster/v3/internal/rest/client"
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
 	internalState "github.com/canonical/microcluster/v3/internal/state"
 	"github.com/canonical/microcluster/v3/rest"
 	"github.com/canonical/microcluster/v3/rest/access"
@@ -186,6 +187,9 @@ func HandleEndpoint(state state.State, mux *mux.Router, version string, e rest.E
 		url = filepath.Join(url, e.Path)
 	}
 
+	// Only the internal database endpoint hands its connections over to dqlite. Other endpoints may share its path.
+	isDatabaseEndpoint := version == string(internalTypes.InternalEndpoint) && e.Path == "database"
+
 	route := mux.HandleFunc(url, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -224,9 +228,26 @@ func HandleEndpoint(state state.State, mux *mux.Router, version string, e rest.E
 			}
 		}
 
+		// Return Unavailable Error (503) if the endpoint requires an API extension that is not yet supported by every cluster member.
+		if e.RequiredExtension != "" {
+			supported, err := state.ClusterHasExtension(r.Context(), e.RequiredExtension)
+			if err == nil && !supported {
+				err = api.StatusErrorf(http.StatusServiceUnavailable, "API extension %q is not yet supported by all cluster members", e.RequiredExtension)
+			}
+
+			if err != nil {
+				err := response.SmartError(err).Render(w, r)
+				if err != nil {
+					logger.Error("Failed to write HTTP response", logger.Ctx{"url": r.URL, "err": err})
+				}
+
+				return
+			}
+		}
+
 		// If the request is a database request, the connection should be hijacked.
 		handleRequest := handleAPIRequest
-		if e.Path == "database" {
+		if isDatabaseEndpoint {
 			handleRequest = handleDatabaseRequest
 		}
 
@@ -258,7 +279,7 @@ func HandleEndpoint(state state.State, mux *mux.Router, version string, e rest.E
 		// In case the database request handler doesn't return an EmptySyncResponse
 		// we can ensure that the connection wasn't yet hijacked and the actual error
 		// can be safely returned to the caller.
-		if e.Path != "database" || (e.Path == "database" && resp != response.EmptySyncResponse) {
+		if !isDatabaseEndpoint || resp != response.EmptySyncResponse {
 			err := resp.Render(w, r)
 			if err != nil {
 				err := response.InternalError(err).Render(w, r)
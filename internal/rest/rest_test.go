@@ -0,0 +1,102 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/canonical/lxd/lxd/response"
+	"github.com/canonical/lxd/shared/api"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/suite"
+
+	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
+	internalState "github.com/canonical/microcluster/v3/internal/state"
+	"github.com/canonical/microcluster/v3/internal/trust"
+	"github.com/canonical/microcluster/v3/rest"
+	"github.com/canonical/microcluster/v3/state"
+)
+
+type restSuite struct {
+	suite.Suite
+}
+
+func TestRestSuite(t *testing.T) {
+	suite.Run(t, new(restSuite))
+}
+
+// Ensures public endpoints sharing the path of the internal database endpoint are handled like any other endpoint.
+func (t *restSuite) Test_handleEndpointDatabasePath() {
+	s := &internalState.InternalState{
+		Context:         context.Background(),
+		InternalName:    func() string { return "n0" },
+		InternalAddress: func() *api.URL { return api.NewURL().Host("10.0.0.0:8443") },
+		InternalRemotes: func() *trust.Remotes { return &trust.Remotes{} },
+	}
+
+	tests := []struct {
+		name         string
+		target       string
+		allowed      bool
+		expectStatus int
+		expectRan    bool
+	}{
+		{
+			name:         "Request targeting this cluster member",
+			target:       "n0",
+			allowed:      true,
+			expectStatus: http.StatusOK,
+			expectRan:    true,
+		},
+		{
+			name:         "Request without a target",
+			allowed:      true,
+			expectStatus: http.StatusOK,
+			expectRan:    true,
+		},
+		{
+			name:         "Request denied by the access handler",
+			target:       "n0",
+			allowed:      false,
+			expectStatus: http.StatusForbidden,
+			expectRan:    false,
+		},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		ran := false
+		endpoint := rest.Endpoint{
+			AllowedBeforeInit: true,
+			Path:              "database",
+			Get: rest.EndpointAction{
+				Handler: func(s state.State, r *http.Request) response.Response {
+					ran = true
+					return response.SyncResponse(true, nil)
+				},
+				AccessHandler: func(s state.State, r *http.Request) (bool, response.Response) {
+					return c.allowed, nil
+				},
+				ProxyTarget: true,
+			},
+		}
+
+		router := mux.NewRouter()
+		HandleEndpoint(s, router, string(internalTypes.PublicEndpoint), endpoint)
+
+		url := "/" + string(internalTypes.PublicEndpoint) + "/database"
+		if c.target != "" {
+			url += "?target=" + c.target
+		}
+
+		req := httptest.NewRequest("GET", url, nil)
+		req.RemoteAddr = "@"
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		t.Equal(c.expectStatus, rec.Code)
+		t.Equal(c.expectRan, ran)
+	}
+}
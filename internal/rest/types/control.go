@@ -11,4 +11,10 @@ type Control struct {
 	JoinToken  string            `json:"join_token" yaml:"join_token"`
 	Address    types.AddrPort    `json:"address" yaml:"address"`
 	Name       string            `json:"name" yaml:"name"`
+
+	// FailureDomain is an optional label used to spread dqlite voters across failure domains.
+	FailureDomain string `json:"failure_domain" yaml:"failure_domain"`
+
+	// RestoreDump is an optional SQL dump of the database of another cluster to restore when bootstrapping.
+	RestoreDump string `json:"restore_dump" yaml:"restore_dump"`
 }
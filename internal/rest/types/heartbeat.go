@@ -13,4 +13,16 @@ type HeartbeatInfo struct {
 	ClusterMembers    map[string]types.ClusterMember `json:"cluster_members"     yaml:"cluster_members"`
 	LeaderAddress     string                         `json:"leader_address"      yaml:"leader_address"`
 	DqliteRoles       map[string]string              `json:"dqlite_roles"        yaml:"dqlite_roles"`
+
+	// MemberPayloads holds the last heartbeat payload received from each cluster member, keyed by member name.
+	MemberPayloads map[string]types.HeartbeatPayload `json:"member_payloads" yaml:"member_payloads"`
+}
+
+// HeartbeatResponse is the response of a cluster member to a heartbeat sent by the leader.
+type HeartbeatResponse struct {
+	// Version is the version of the project running on the cluster member.
+	Version string `json:"version" yaml:"version"`
+
+	// Payload is the free-form data attached by the cluster member to its heartbeat response.
+	Payload types.HeartbeatPayload `json:"payload" yaml:"payload"`
 }
@@ -37,6 +37,21 @@ const (
 
 	// OnDaemonConfigUpdate is run after the local daemon received a config update.
 	OnDaemonConfigUpdate HookType = "on-daemon-config-update"
+
+	// OnClusterConfigUpdate is run on all cluster members after the cluster-wide config is updated.
+	OnClusterConfigUpdate HookType = "on-cluster-config-update"
+
+	// OnMemberRename is run on all cluster members after a cluster member is renamed.
+	OnMemberRename HookType = "on-member-rename"
+
+	// OnMemberDown is run on all reachable cluster members after a cluster member becomes suspect or offline.
+	OnMemberDown HookType = "on-member-down"
+
+	// OnMemberUp is run on all reachable cluster members after a suspect or offline cluster member is back online.
+	OnMemberUp HookType = "on-member-up"
+
+	// OnMemberEvicted is run on all reachable cluster members after an offline cluster member is automatically evicted.
+	OnMemberEvicted HookType = "on-member-evicted"
 )
 
 // HookRemoveMemberOptions holds configuration pertaining to the PreRemove and PostRemove hooks.
@@ -50,3 +65,18 @@ type HookNewMemberOptions struct {
 	// Name is the name of the new cluster member that joined the cluster, triggering this hook.
 	NewMember types.ClusterMemberLocal `json:"new_member" yaml:"new_member"`
 }
+
+// HookRenameMemberOptions holds configuration pertaining to the OnMemberRename hook.
+type HookRenameMemberOptions struct {
+	// OldName is the previous name of the renamed cluster member.
+	OldName string `json:"old_name" yaml:"old_name"`
+
+	// NewName is the new name of the renamed cluster member.
+	NewName string `json:"new_name" yaml:"new_name"`
+}
+
+// HookMemberStatusOptions holds configuration pertaining to the OnMemberDown, OnMemberUp and OnMemberEvicted hooks.
+type HookMemberStatusOptions struct {
+	// Member is the cluster member whose heartbeat status changed or that was evicted, triggering this hook.
+	Member types.ClusterMember `json:"member" yaml:"member"`
+}
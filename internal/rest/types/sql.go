@@ -8,6 +8,15 @@ type SQLDump struct {
 // SQLQuery represents a SQL query.
 type SQLQuery struct {
 	Query string `json:"query" yaml:"query"`
+
+	// Args are the values of the parameters of a query consisting of a single statement.
+	Args []any `json:"args" yaml:"args"`
+
+	// Atomic runs all statements of the query in a single transaction, rather than one transaction per statement.
+	Atomic bool `json:"atomic" yaml:"atomic"`
+
+	// ReadOnly prevents the query from modifying the database.
+	ReadOnly bool `json:"read_only" yaml:"read_only"`
 }
 
 // SQLBatch represents a batch of SQL results.
@@ -35,9 +35,42 @@ type Hooks struct {
 	// OnHeartbeat is run after a successful heartbeat round.
 	OnHeartbeat func(ctx context.Context, s State, roleStatus map[string]types.RoleStatus) error
 
+	// OnHeartbeatPayloads is run on the leader after a successful heartbeat round, following OnHeartbeat.
+	// The payloads map holds the last heartbeat payload of each cluster member, keyed by member name.
+	// It is a separate hook rather than an extra argument to OnHeartbeat, so that existing OnHeartbeat hooks keep
+	// compiling unchanged.
+	OnHeartbeatPayloads func(ctx context.Context, s State, payloads map[string]types.HeartbeatPayload) error
+
+	// HeartbeatPayload is run on each cluster member when it receives a heartbeat, and on the leader when it begins a
+	// heartbeat round. The returned payload is sent back to the leader, which stores it with the cluster member's record.
+	HeartbeatPayload func(ctx context.Context, s State) (types.HeartbeatPayload, error)
+
 	// OnNewMember is run on each peer after a new cluster member has joined and executed their 'PreJoin' hook.
 	OnNewMember func(ctx context.Context, s State, newMember types.ClusterMemberLocal) error
 
 	// OnDaemonConfigUpdate is a post-action hook that is run on all cluster members when any cluster member receives a local configuration update.
 	OnDaemonConfigUpdate func(ctx context.Context, s State, config types.DaemonConfig) error
+
+	// OnClusterConfigUpdate is a post-action hook that is run on all cluster members when the cluster-wide configuration is updated.
+	OnClusterConfigUpdate func(ctx context.Context, s State, config types.ClusterConfig) error
+
+	// OnMemberRename is a post-action hook that is run on all cluster members after a cluster member is renamed.
+	OnMemberRename func(ctx context.Context, s State, oldName string, newName string) error
+
+	// OnMemberDown is run on all reachable cluster members after a cluster member misses enough consecutive heartbeats
+	// to become suspect or offline. The member's heartbeat status holds its new state.
+	OnMemberDown func(ctx context.Context, s State, member types.ClusterMember) error
+
+	// OnMemberUp is run on all reachable cluster members after a suspect or offline cluster member responds to a heartbeat again.
+	OnMemberUp func(ctx context.Context, s State, member types.ClusterMember) error
+
+	// OnUpgradeRequired is run on a cluster member once it finds out that it is behind the rest of the cluster, either
+	// from a heartbeat or when another cluster member refuses its database connection. It can be used to trigger a refresh.
+	// Returning nil reports that an upgrade was started. On error, the cluster member keeps needing an upgrade and the
+	// hook is run again the next time the cluster member finds out it is behind.
+	OnUpgradeRequired func(ctx context.Context, s State) error
+
+	// OnMemberEvicted is run on all reachable cluster members after the leader automatically removed a cluster member
+	// that was offline for longer than the configured eviction threshold. The member holds its last known state.
+	OnMemberEvicted func(ctx context.Context, s State, member types.ClusterMember) error
 }
@@ -2,6 +2,7 @@ package state
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"time"
 
@@ -9,6 +10,7 @@ import (
 	"github.com/canonical/lxd/shared/api"
 
 	"github.com/canonical/microcluster/v3/client"
+	"github.com/canonical/microcluster/v3/cluster"
 	internalConfig "github.com/canonical/microcluster/v3/internal/config"
 	"github.com/canonical/microcluster/v3/internal/db"
 	"github.com/canonical/microcluster/v3/internal/endpoints"
@@ -51,9 +53,15 @@ type State interface {
 	// Leader returns a client to the dqlite cluster leader.
 	Leader() (*client.Client, error)
 
+	// ClusterMembersBySelector returns the cluster members whose labels match the given label selector.
+	ClusterMembersBySelector(ctx context.Context, selector string) ([]types.ClusterMember, error)
+
 	// HasExtension returns whether the given API extension is supported.
 	HasExtension(ext string) bool
 
+	// ClusterHasExtension returns whether the given API extension is supported by all cluster members.
+	ClusterHasExtension(ctx context.Context, ext string) (bool, error)
+
 	// ExtensionServers returns an immutable list of the daemon's additional listeners.
 	ExtensionServers() []string
 }
@@ -87,6 +95,12 @@ type InternalState struct {
 	// ReloadCert reloads the given keypair from the state directory.
 	ReloadCert func(name types.CertificateName) error
 
+	// ReplaceServerCert replaces the server keypair with the given one, even after initialization.
+	ReplaceServerCert func(cert []byte, key []byte) error
+
+	// UpdateAddress moves the core API listener and dqlite to the given address.
+	UpdateAddress func(address types.AddrPort) error
+
 	// StopListeners stops the network listeners and the fsnotify listener.
 	StopListeners func() error
 
@@ -96,6 +110,33 @@ type InternalState struct {
 	// Runtime extensions.
 	Extensions extensions.Extensions
 
+	// ClusterConfigKeys maps the supported cluster-wide configuration keys to their validators.
+	ClusterConfigKeys map[string]func(value string) error
+
+	// HeartbeatSuspectThreshold is the number of consecutive missed heartbeats after which a cluster member is suspect.
+	HeartbeatSuspectThreshold int
+
+	// HeartbeatOfflineThreshold is the number of consecutive missed heartbeats after which a cluster member is offline.
+	HeartbeatOfflineThreshold int
+
+	// AutoEvictAfter is the time after which an offline cluster member is automatically evicted, or zero if disabled.
+	AutoEvictAfter time.Duration
+
+	// AutoEvictMinVoters is the number of online voters that must remain for an automatic eviction to take place.
+	AutoEvictMinVoters int
+
+	// BackupInterval is the interval between scheduled backups taken by the leader, or zero if disabled.
+	BackupInterval time.Duration
+
+	// BackupKeepLast is the number of most recent scheduled backups to keep, or no limit if negative.
+	BackupKeepLast int
+
+	// BackupMaxAge is the age after which scheduled backups are removed, or zero if disabled.
+	BackupMaxAge time.Duration
+
+	// SQLWritesUnixOnly restricts SQL statements that modify the database to requests over the unix socket.
+	SQLWritesUnixOnly bool
+
 	// Hooks contain external implementations that are triggered by specific cluster actions.
 	Hooks *Hooks
 
@@ -161,6 +202,38 @@ func (s *InternalState) HasExtension(ext string) bool {
 	return s.Extensions.HasExtension(ext)
 }
 
+// ClusterHasExtension returns whether the given API extension is supported by all cluster members, according to the
+// API extensions each cluster member has recorded in the database. During a rolling upgrade, this will only report true
+// once every cluster member has been upgraded to a version supporting the extension.
+func (s *InternalState) ClusterHasExtension(ctx context.Context, ext string) (bool, error) {
+	if !s.HasExtension(ext) {
+		return false, nil
+	}
+
+	supported := true
+	err := s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		dbClusterMembers, err := cluster.GetCoreClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		for _, clusterMember := range dbClusterMembers {
+			if !clusterMember.APIExtensions.HasExtension(ext) {
+				supported = false
+
+				return nil
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("Failed to get API extensions of cluster members: %w", err)
+	}
+
+	return supported, nil
+}
+
 // Cluster returns a client for every member of a cluster, except
 // this one.
 // All requests made by the client will have the UserAgentNotifier header set
@@ -228,6 +301,44 @@ func (s *InternalState) Leader() (*client.Client, error) {
 	return &client.Client{Client: *c}, nil
 }
 
+// ClusterMembersBySelector returns the database record of all cluster members whose labels match the given selector.
+// See types.ParseLabelSelector for the supported syntax.
+func (s *InternalState) ClusterMembersBySelector(ctx context.Context, selector string) ([]types.ClusterMember, error) {
+	labelSelector, err := types.ParseLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var clusterMembers []types.ClusterMember
+	err = s.Database().Transaction(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		dbClusterMembers, err := cluster.GetCoreClusterMembers(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		clusterMembers = make([]types.ClusterMember, 0, len(dbClusterMembers))
+		for _, clusterMember := range dbClusterMembers {
+			if !labelSelector.Matches(clusterMember.Labels) {
+				continue
+			}
+
+			apiClusterMember, err := clusterMember.ToAPI()
+			if err != nil {
+				return err
+			}
+
+			clusterMembers = append(clusterMembers, *apiClusterMember)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return clusterMembers, nil
+}
+
 // ToInternal returns the underlying InternalState from the exposed State interface.
 func ToInternal(s State) (*InternalState, error) {
 	internal, ok := s.(*InternalState)
@@ -18,6 +18,9 @@ type OS struct {
 	DatabaseDir     string
 	TrustDir        string
 	CertificatesDir string
+	BackupsDir      string
+	SnapshotsDir    string
+	SQLAuditLog     string
 	LogFile         string
 }
 
@@ -34,6 +37,9 @@ func DefaultOS(stateDir string, createDir bool) (*OS, error) {
 		DatabaseDir:     filepath.Join(stateDir, "database"),
 		TrustDir:        filepath.Join(stateDir, "truststore"),
 		CertificatesDir: filepath.Join(stateDir, "certificates"),
+		BackupsDir:      filepath.Join(stateDir, "backups"),
+		SnapshotsDir:    filepath.Join(stateDir, "upgrade_snapshots"),
+		SQLAuditLog:     filepath.Join(stateDir, "sql_audit.log"),
 		LogFile:         "",
 	}
 
@@ -54,6 +60,8 @@ func (s *OS) init(createDir bool) error {
 		{s.DatabaseDir, 0700},
 		{s.TrustDir, 0700},
 		{s.CertificatesDir, 0700},
+		{s.BackupsDir, 0700},
+		{s.SnapshotsDir, 0700},
 	}
 
 	for _, dir := range dirs {
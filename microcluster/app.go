@@ -19,6 +19,7 @@ import (
 	"github.com/canonical/microcluster/v3/client"
 	"github.com/canonical/microcluster/v3/cluster"
 	"github.com/canonical/microcluster/v3/internal/daemon"
+	"github.com/canonical/microcluster/v3/internal/db"
 	"github.com/canonical/microcluster/v3/internal/recover"
 	internalClient "github.com/canonical/microcluster/v3/internal/rest/client"
 	internalTypes "github.com/canonical/microcluster/v3/internal/rest/types"
@@ -44,6 +45,29 @@ type Args struct {
 	Proxy  func(*http.Request) (*url.URL, error)
 }
 
+// SQLOptions contains options for executing a SQL query with ExecSQL.
+type SQLOptions struct {
+	// Args are the values of the parameters of a query consisting of a single statement.
+	Args []any
+
+	// Atomic runs all statements of the query in a single transaction, rather than one transaction per statement.
+	Atomic bool
+
+	// ReadOnly prevents the query from modifying the database.
+	ReadOnly bool
+}
+
+// InitOption sets an optional property of the cluster member when bootstrapping or joining a cluster.
+type InitOption func(control *internalTypes.Control)
+
+// WithFailureDomain sets the failure domain of the cluster member, such as its rack or availability zone. dqlite voters
+// are spread across failure domains.
+func WithFailureDomain(failureDomain string) InitOption {
+	return func(control *internalTypes.Control) {
+		control.FailureDomain = failureDomain
+	}
+}
+
 // App returns an instance of MicroCluster with a newly initialized filesystem if one does not exist.
 func App(args Args) (*MicroCluster, error) {
 	if args.StateDir == "" {
@@ -169,7 +193,37 @@ func (m *MicroCluster) Ready(ctx context.Context) error {
 }
 
 // NewCluster bootstrapps a brand new cluster with this daemon as its only member.
-func (m *MicroCluster) NewCluster(ctx context.Context, name string, address string, config map[string]string) error {
+func (m *MicroCluster) NewCluster(ctx context.Context, name string, address string, config map[string]string, opts ...InitOption) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	addr, err := types.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("Received invalid address %q: %w", address, err)
+	}
+
+	control := internalTypes.Control{Bootstrap: true, Address: addr, Name: name, InitConfig: config}
+	for _, opt := range opts {
+		opt(&control)
+	}
+
+	return c.ControlDaemon(ctx, control)
+}
+
+// RestoreFromBackup bootstrapps a brand new cluster with this daemon as its only member, with the database restored
+// from the given backup. The backup can either be an archive written by Backup, or a plain SQL dump of the database.
+// The db_backup archives of the dqlite directory written to the state directory when recovering from quorum loss with
+// RecoverFromQuorumLoss are not supported. They can only be restored by extracting them into the state directory of
+// the cluster member that created them.
+// The backup must not be ahead of this daemon's schema version or API extensions, and any schema updates this daemon
+// has over the backup are applied after restoring it. Of the cluster members in the backup, only the record of the
+// cluster member with the given name is kept, if it exists.
+//
+// This is also how the cluster is rolled back to a snapshot taken before applying schema updates, with the previous
+// version of the project. Snapshots are kept in the upgrade_snapshots directory of the state directory.
+func (m *MicroCluster) RestoreFromBackup(ctx context.Context, name string, address string, backup io.Reader, config map[string]string, opts ...InitOption) error {
 	c, err := m.LocalClient()
 	if err != nil {
 		return err
@@ -180,11 +234,21 @@ func (m *MicroCluster) NewCluster(ctx context.Context, name string, address stri
 		return fmt.Errorf("Received invalid address %q: %w", address, err)
 	}
 
-	return c.ControlDaemon(ctx, internalTypes.Control{Bootstrap: true, Address: addr, Name: name, InitConfig: config})
+	dbBackup, err := db.ReadBackup(backup)
+	if err != nil {
+		return err
+	}
+
+	control := internalTypes.Control{Bootstrap: true, Address: addr, Name: name, InitConfig: config, RestoreDump: dbBackup.Dump}
+	for _, opt := range opts {
+		opt(&control)
+	}
+
+	return c.ControlDaemon(ctx, control)
 }
 
 // JoinCluster joins an existing cluster with a join token supplied by an existing cluster member.
-func (m *MicroCluster) JoinCluster(ctx context.Context, name string, address string, token string, initConfig map[string]string) error {
+func (m *MicroCluster) JoinCluster(ctx context.Context, name string, address string, token string, initConfig map[string]string, opts ...InitOption) error {
 	c, err := m.LocalClient()
 	if err != nil {
 		return err
@@ -195,7 +259,12 @@ func (m *MicroCluster) JoinCluster(ctx context.Context, name string, address str
 		return fmt.Errorf("Received invalid address %q: %w", address, err)
 	}
 
-	return c.ControlDaemon(ctx, internalTypes.Control{JoinToken: token, Address: addr, Name: name, InitConfig: initConfig})
+	control := internalTypes.Control{JoinToken: token, Address: addr, Name: name, InitConfig: initConfig}
+	for _, opt := range opts {
+		opt(&control)
+	}
+
+	return c.ControlDaemon(ctx, control)
 }
 
 // GetDqliteClusterMembers retrieves the current local cluster configuration
@@ -215,7 +284,8 @@ func (m *MicroCluster) GetDqliteClusterMembers() ([]cluster.DqliteMember, error)
 //     which was most recently the leader)
 //
 // RecoverFromQuorumLoss will take a database backup before attempting the
-// recovery operation.
+// recovery operation. This backup is a copy of the dqlite directory, and
+// cannot be restored with RestoreFromBackup.
 //
 // RecoverFromQuorumLoss should be invoked _exactly once_ for the entire cluster.
 // This function creates a gz-compressed tarball and returns its path. This
@@ -358,16 +428,25 @@ func (m *MicroCluster) RemoteClientWithCert(address string, cert *x509.Certifica
 	return c, nil
 }
 
+// Backup writes a consistent backup of the live database to the given writer as a gzip compressed tarball.
+// The tarball contains the metadata of the backup, as well as a SQL dump of the database. The dump is taken in a
+// read-only transaction, and the daemon holds it in memory in full while sending it.
+func (m *MicroCluster) Backup(ctx context.Context, w io.Writer) error {
+	c, err := m.LocalClient()
+	if err != nil {
+		return err
+	}
+
+	return c.GetDatabaseBackup(ctx, w)
+}
+
 // SQL performs either a GET or POST on /internal/sql with a given query. This is a useful helper for using direct SQL.
+// Besides SQL statements, the query can be one of the meta-commands ".dump", ".schema", ".tables",
+// ".indexes [<table>]", ".count <table>" or ".explain <query>".
 func (m *MicroCluster) SQL(ctx context.Context, query string) (string, *internalTypes.SQLBatch, error) {
-	if query == "-" {
-		// Read from stdin
-		bytes, err := io.ReadAll(os.Stdin)
-		if err != nil {
-			return "", nil, fmt.Errorf("Failed to read from stdin: %w", err)
-		}
-
-		query = string(bytes)
+	query, err := readSQLQuery(query)
+	if err != nil {
+		return "", nil, err
 	}
 
 	c, err := m.LocalClient()
@@ -392,3 +471,40 @@ func (m *MicroCluster) SQL(ctx context.Context, query string) (string, *internal
 
 	return "", batch, err
 }
+
+// ExecSQL performs a POST on /internal/sql with the given query, which is read from stdin if it is "-".
+// The query may contain arguments for its parameters, and may be run atomically in a single transaction, or in read-only mode.
+func (m *MicroCluster) ExecSQL(ctx context.Context, query string, opts SQLOptions) (*internalTypes.SQLBatch, error) {
+	query, err := readSQLQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := m.LocalClient()
+	if err != nil {
+		return nil, err
+	}
+
+	data := internalTypes.SQLQuery{
+		Query:    query,
+		Args:     opts.Args,
+		Atomic:   opts.Atomic,
+		ReadOnly: opts.ReadOnly,
+	}
+
+	return internalClient.PostSQL(ctx, &c.Client, data)
+}
+
+// readSQLQuery returns the given query, or reads it from stdin if it is "-".
+func readSQLQuery(query string) (string, error) {
+	if query != "-" {
+		return query, nil
+	}
+
+	bytes, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read from stdin: %w", err)
+	}
+
+	return string(bytes), nil
+}
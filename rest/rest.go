@@ -36,6 +36,10 @@ type Endpoint struct {
 
 	AllowedDuringShutdown bool // Whether we should return Unavailable Error (503) if daemon is shutting down.
 	AllowedBeforeInit     bool // Whether we should return Unavailabel Error (503) if the daemon has not been initialized (is not yet part of a cluster).
+
+	// RequiredExtension is an API extension that must be supported by all cluster members before the endpoint can be used.
+	// Until then, the endpoint will return Unavailable Error (503). If empty, the endpoint is always available.
+	RequiredExtension string
 }
 
 // Resources represents all the resources served over the same path.
@@ -16,6 +16,25 @@ type ClusterMember struct {
 	Status                MemberStatus          `json:"status" yaml:"status"`
 	Extensions            extensions.Extensions `json:"extensions" yaml:"extensions"`
 	Secret                string                `json:"secret" yaml:"secret"`
+	FailureDomain         string                `json:"failure_domain" yaml:"failure_domain"`
+	Labels                Labels                `json:"labels" yaml:"labels"`
+	HeartbeatStatus       MemberStatus          `json:"heartbeat_status" yaml:"heartbeat_status"`
+	HeartbeatFailures     int                   `json:"heartbeat_failures" yaml:"heartbeat_failures"`
+	HeartbeatPayload      HeartbeatPayload      `json:"heartbeat_payload" yaml:"heartbeat_payload"`
+	HeartbeatLatency      time.Duration         `json:"heartbeat_latency" yaml:"heartbeat_latency"`
+	HeartbeatError        string                `json:"heartbeat_error" yaml:"heartbeat_error"`
+	UpgradeStatus         MemberStatus          `json:"upgrade_status" yaml:"upgrade_status"`
+	Version               string                `json:"version" yaml:"version"`
+}
+
+// ClusterMemberHealth represents the heartbeat health metrics of a cluster member, as recorded by the leader.
+type ClusterMemberHealth struct {
+	Name              string        `json:"name" yaml:"name"`
+	Status            MemberStatus  `json:"status" yaml:"status"`
+	LastHeartbeat     time.Time     `json:"last_heartbeat" yaml:"last_heartbeat"`
+	Latency           time.Duration `json:"latency" yaml:"latency"`
+	ConsecutiveMisses int           `json:"consecutive_misses" yaml:"consecutive_misses"`
+	LastError         string        `json:"last_error" yaml:"last_error"`
 }
 
 // ClusterMemberLocal represents local information about a new cluster member.
@@ -25,6 +44,27 @@ type ClusterMemberLocal struct {
 	Certificate X509Certificate `json:"certificate" yaml:"certificate"`
 }
 
+// ClusterMemberPatch represents the modifiable fields of a cluster member.
+type ClusterMemberPatch struct {
+	// Labels to set on the cluster member. A label with an empty value is removed.
+	Labels Labels `json:"labels" yaml:"labels"`
+}
+
+// ClusterMemberRole represents the dqlite role to assign to a cluster member.
+type ClusterMemberRole struct {
+	Role string `json:"role" yaml:"role"`
+}
+
+// ClusterMemberRename represents the new name to give to a cluster member.
+type ClusterMemberRename struct {
+	Name string `json:"name" yaml:"name"`
+}
+
+// ClusterMemberAddress represents the new address to give to a cluster member.
+type ClusterMemberAddress struct {
+	Address AddrPort `json:"address" yaml:"address"`
+}
+
 // MemberStatus represents the online status of a cluster member.
 type MemberStatus string
 
@@ -46,6 +86,12 @@ const (
 	// MemberUnreachable should be the MemberStatus when we were not able to connect to the node.
 	MemberUnreachable MemberStatus = "UNREACHABLE"
 
+	// MemberSuspect should be the heartbeat status of a node that has recently missed some heartbeats.
+	MemberSuspect MemberStatus = "SUSPECT"
+
+	// MemberOffline should be the heartbeat status of a node that has missed enough consecutive heartbeats to be considered down.
+	MemberOffline MemberStatus = "OFFLINE"
+
 	// MemberNotTrusted should be the MemberStatus when there is no local yaml entry for this node.
 	MemberNotTrusted MemberStatus = "NOT TRUSTED"
 
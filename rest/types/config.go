@@ -5,4 +5,9 @@ type DaemonConfig struct {
 	Name    string                  `json:"name" yaml:"name"`
 	Address AddrPort                `json:"address" yaml:"address"`
 	Servers map[string]ServerConfig `json:"servers" yaml:"servers"`
+
+	FailureDomain string `json:"failure_domain,omitempty" yaml:"failure_domain,omitempty"`
 }
+
+// ClusterConfig is the cluster-wide configuration shared by all cluster members.
+type ClusterConfig map[string]string
@@ -1,5 +1,11 @@
 package types
 
+import (
+	"time"
+
+	"github.com/canonical/microcluster/v3/internal/extensions"
+)
+
 // DatabaseStatus is the current status of the database.
 type DatabaseStatus string
 
@@ -19,3 +25,198 @@ const (
 	// DatabaseOffline indicates that the database is offline.
 	DatabaseOffline DatabaseStatus = "Database is offline"
 )
+
+// DatabaseBackup is the metadata describing a backup of the database.
+type DatabaseBackup struct {
+	// CreatedAt is the time at which the backup was taken.
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+
+	// Name is the name of the cluster member that took the backup.
+	Name string `json:"name" yaml:"name"`
+
+	// Version is the version of the project running on the cluster member that took the backup.
+	Version string `json:"version" yaml:"version"`
+
+	// SchemaInternalVersion is the internal schema version of the database at the time of the backup.
+	SchemaInternalVersion uint64 `json:"schema_internal_version" yaml:"schema_internal_version"`
+
+	// SchemaExternalVersion is the external schema version of the database at the time of the backup.
+	SchemaExternalVersion uint64 `json:"schema_external_version" yaml:"schema_external_version"`
+
+	// APIExtensions is the set of API extensions supported at the time of the backup.
+	APIExtensions extensions.Extensions `json:"api_extensions" yaml:"api_extensions"`
+
+	// Members is the list of cluster members at the time of the backup.
+	Members []DatabaseBackupMember `json:"members" yaml:"members"`
+}
+
+// DatabaseBackupMember is the record of a cluster member in a database backup.
+type DatabaseBackupMember struct {
+	Name    string   `json:"name" yaml:"name"`
+	Address AddrPort `json:"address" yaml:"address"`
+	Role    string   `json:"role" yaml:"role"`
+}
+
+// DatabaseBackups is the list of scheduled backups of the database stored on a cluster member.
+type DatabaseBackups struct {
+	// LastAttempt is the time of the last attempt at a scheduled backup by this cluster member.
+	LastAttempt time.Time `json:"last_attempt" yaml:"last_attempt"`
+
+	// LastError is the error of the last attempt at a scheduled backup, or empty if it succeeded.
+	LastError string `json:"last_error" yaml:"last_error"`
+
+	// Backups is the list of backups stored on this cluster member, from oldest to newest.
+	Backups []DatabaseBackupFile `json:"backups" yaml:"backups"`
+}
+
+// DatabaseBackupFile is a backup archive stored on a cluster member.
+type DatabaseBackupFile struct {
+	Name      string    `json:"name" yaml:"name"`
+	Size      int64     `json:"size" yaml:"size"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// DatabaseChangeOperation is the kind of change made to a row of a watched table.
+type DatabaseChangeOperation string
+
+const (
+	// DatabaseChangeInsert indicates a row was inserted.
+	DatabaseChangeInsert DatabaseChangeOperation = "insert"
+
+	// DatabaseChangeUpdate indicates a row was updated.
+	DatabaseChangeUpdate DatabaseChangeOperation = "update"
+
+	// DatabaseChangeDelete indicates a row was deleted.
+	DatabaseChangeDelete DatabaseChangeOperation = "delete"
+)
+
+// DatabaseChange is an event describing a committed change to a row of a watched table.
+type DatabaseChange struct {
+	// ID is the position of the change in the cluster-wide change log. IDs increase with each change.
+	ID int64 `json:"id" yaml:"id"`
+
+	// Table is the name of the table that was changed.
+	Table string `json:"table" yaml:"table"`
+
+	// Operation is the kind of change made to the row.
+	Operation DatabaseChangeOperation `json:"operation" yaml:"operation"`
+
+	// RowID is the rowid of the changed row.
+	RowID int64 `json:"row_id" yaml:"row_id"`
+
+	// CreatedAt is the time at which the change was made.
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// DatabaseInfo is diagnostic information about the database, as seen from a cluster member.
+type DatabaseInfo struct {
+	// Status is the status of the database on this cluster member.
+	Status DatabaseStatus `json:"status" yaml:"status"`
+
+	// NodeID is the dqlite node ID of this cluster member.
+	NodeID uint64 `json:"node_id" yaml:"node_id"`
+
+	// Leader is the address of the current dqlite leader.
+	Leader string `json:"leader" yaml:"leader"`
+
+	// Nodes is the list of dqlite nodes in the raft configuration.
+	Nodes []DatabaseNode `json:"nodes" yaml:"nodes"`
+
+	// PageSize is the size of a database page in bytes.
+	PageSize int64 `json:"page_size" yaml:"page_size"`
+
+	// PageCount is the number of pages in the database.
+	PageCount int64 `json:"page_count" yaml:"page_count"`
+
+	// FreePageCount is the number of unused pages in the database.
+	FreePageCount int64 `json:"free_page_count" yaml:"free_page_count"`
+
+	// Size is the size of the database in bytes.
+	Size int64 `json:"size" yaml:"size"`
+
+	// Tables is the list of tables in the database along with their row counts, sorted by name.
+	Tables []DatabaseTable `json:"tables" yaml:"tables"`
+
+	// SlowTransactions is the list of the most recent slow transactions on this cluster member, from oldest to newest.
+	SlowTransactions []DatabaseSlowTransaction `json:"slow_transactions" yaml:"slow_transactions"`
+}
+
+// DatabaseNode is a dqlite node in the raft configuration.
+type DatabaseNode struct {
+	ID      uint64 `json:"id" yaml:"id"`
+	Address string `json:"address" yaml:"address"`
+	Role    string `json:"role" yaml:"role"`
+}
+
+// DatabaseTable is a table in the database.
+type DatabaseTable struct {
+	Name string `json:"name" yaml:"name"`
+	Rows int64  `json:"rows" yaml:"rows"`
+}
+
+// DatabaseSlowTransaction is a transaction which took longer than the slow transaction threshold.
+type DatabaseSlowTransaction struct {
+	// Time is the time at which the transaction started.
+	Time time.Time `json:"time" yaml:"time"`
+
+	// Duration is how long the transaction took, including retries.
+	Duration time.Duration `json:"duration" yaml:"duration"`
+
+	// Caller is the function that ran the transaction, along with its source location.
+	Caller string `json:"caller" yaml:"caller"`
+
+	// Error is the error the transaction failed with, if any.
+	Error string `json:"error" yaml:"error"`
+}
+
+// DatabaseSchemaUpdateType is the type of a schema update.
+type DatabaseSchemaUpdateType string
+
+const (
+	// DatabaseSchemaInternal is a schema update of microcluster itself.
+	DatabaseSchemaInternal DatabaseSchemaUpdateType = "internal"
+
+	// DatabaseSchemaExternal is a schema update of the project using microcluster, applied after all internal updates.
+	DatabaseSchemaExternal DatabaseSchemaUpdateType = "external"
+)
+
+// DatabaseSchema is the status of the schema updates of the database, as seen from a cluster member.
+type DatabaseSchema struct {
+	// Status is the status of the database on this cluster member.
+	Status DatabaseStatus `json:"status" yaml:"status"`
+
+	// SchemaInternalVersion is the internal schema version supported by this cluster member.
+	SchemaInternalVersion uint64 `json:"schema_internal_version" yaml:"schema_internal_version"`
+
+	// SchemaExternalVersion is the external schema version supported by this cluster member.
+	SchemaExternalVersion uint64 `json:"schema_external_version" yaml:"schema_external_version"`
+
+	// Applied is the list of schema updates applied to the database, internal updates first.
+	Applied []DatabaseSchemaUpdate `json:"applied" yaml:"applied"`
+
+	// Pending is the list of schema updates supported by this cluster member that have yet to be applied.
+	Pending []DatabaseSchemaUpdate `json:"pending" yaml:"pending"`
+
+	// Members is the list of cluster members along with the schema versions they support.
+	Members []DatabaseSchemaMember `json:"members" yaml:"members"`
+}
+
+// DatabaseSchemaUpdate is a schema update of the database.
+type DatabaseSchemaUpdate struct {
+	// Type is whether the update is internal or external.
+	Type DatabaseSchemaUpdateType `json:"type" yaml:"type"`
+
+	// Version is the schema version of the given type once the update is applied.
+	Version uint64 `json:"version" yaml:"version"`
+
+	// UpdatedAt is the time at which the update was applied, or the zero time if it is pending.
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// DatabaseSchemaMember is the schema versions supported by a cluster member.
+type DatabaseSchemaMember struct {
+	Name                  string `json:"name" yaml:"name"`
+	Role                  string `json:"role" yaml:"role"`
+	SchemaInternalVersion uint64 `json:"schema_internal_version" yaml:"schema_internal_version"`
+	SchemaExternalVersion uint64 `json:"schema_external_version" yaml:"schema_external_version"`
+}
@@ -0,0 +1,39 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// HeartbeatPayload is a set of free-form key/value data that a cluster member attaches to its heartbeat response.
+type HeartbeatPayload map[string]string
+
+// Value implements the driver.Valuer interface to serialize the HeartbeatPayload for database storage.
+func (p HeartbeatPayload) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return "{}", nil
+	}
+
+	return json.Marshal(p)
+}
+
+// Scan implements the sql.Scanner interface to deserialize the HeartbeatPayload from database storage.
+func (p *HeartbeatPayload) Scan(value any) error {
+	if value == nil {
+		*p = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("type assertion to []byte or string failed, incompatible type (%T) for value: %v", value, value)
+	}
+
+	return json.Unmarshal(bytes, p)
+}
@@ -0,0 +1,130 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Labels is a set of free-form key/value attributes of a cluster member.
+type Labels map[string]string
+
+// Value implements the driver.Valuer interface to serialize the Labels for database storage.
+func (l Labels) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "{}", nil
+	}
+
+	return json.Marshal(l)
+}
+
+// Scan implements the sql.Scanner interface to deserialize the Labels from database storage.
+func (l *Labels) Scan(value any) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+
+	var bytes []byte
+	switch v := value.(type) {
+	case []byte:
+		bytes = v
+	case string:
+		bytes = []byte(v)
+	default:
+		return fmt.Errorf("type assertion to []byte or string failed, incompatible type (%T) for value: %v", value, value)
+	}
+
+	return json.Unmarshal(bytes, l)
+}
+
+// ValidateLabel returns an error if the given label key or value contains characters reserved for label selectors.
+// An empty value is valid and removes the label when updating a cluster member.
+func ValidateLabel(key string, value string) error {
+	if key == "" || strings.ContainsAny(key, labelReservedChars) {
+		return fmt.Errorf("Invalid label key %q", key)
+	}
+
+	if strings.ContainsAny(value, labelReservedChars) {
+		return fmt.Errorf("Invalid value %q for label %q", value, key)
+	}
+
+	return nil
+}
+
+// labelReservedChars are the characters that separate the conditions of a label selector.
+const labelReservedChars = "=!, "
+
+// labelRequirement is a single condition of a LabelSelector.
+type labelRequirement struct {
+	key    string
+	value  string
+	op     string
+	negate bool
+}
+
+// LabelSelector is a set of conditions which must all hold for a set of labels to match.
+type LabelSelector struct {
+	requirements []labelRequirement
+}
+
+// ParseLabelSelector parses a comma separated list of label conditions into a LabelSelector.
+// Supported conditions are `key=value` (or `key==value`), `key!=value`, `key` (the label is set) and `!key` (the label is not set).
+// An empty selector matches all labels.
+func ParseLabelSelector(selector string) (LabelSelector, error) {
+	requirements := []labelRequirement{}
+	for _, part := range strings.Split(selector, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var req labelRequirement
+		switch {
+		case strings.Contains(part, "!="):
+			req.key, req.value, _ = strings.Cut(part, "!=")
+			req.op = "="
+			req.negate = true
+		case strings.Contains(part, "=="):
+			req.key, req.value, _ = strings.Cut(part, "==")
+			req.op = "="
+		case strings.Contains(part, "="):
+			req.key, req.value, _ = strings.Cut(part, "=")
+			req.op = "="
+		case strings.HasPrefix(part, "!"):
+			req.key = strings.TrimPrefix(part, "!")
+			req.negate = true
+		default:
+			req.key = part
+		}
+
+		req.key = strings.TrimSpace(req.key)
+		req.value = strings.TrimSpace(req.value)
+		err := ValidateLabel(req.key, req.value)
+		if err != nil {
+			return LabelSelector{}, fmt.Errorf("Invalid label selector condition %q: %w", part, err)
+		}
+
+		requirements = append(requirements, req)
+	}
+
+	return LabelSelector{requirements: requirements}, nil
+}
+
+// Matches returns whether the given labels satisfy every condition of the selector.
+func (s LabelSelector) Matches(labels Labels) bool {
+	for _, req := range s.requirements {
+		value, ok := labels[req.key]
+		match := ok
+		if req.op == "=" {
+			match = ok && value == req.value
+		}
+
+		if match == req.negate {
+			return false
+		}
+	}
+
+	return true
+}
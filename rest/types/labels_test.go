@@ -0,0 +1,82 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type labelsSuite struct {
+	suite.Suite
+}
+
+func TestLabelsSuite(t *testing.T) {
+	suite.Run(t, new(labelsSuite))
+}
+
+func (t *labelsSuite) Test_labelSelector() {
+	labels := Labels{"zone": "a", "gpu": "false"}
+
+	tests := []struct {
+		selector    string
+		expectMatch bool
+		expectErr   bool
+	}{
+		{selector: "", expectMatch: true},
+		{selector: "zone=a", expectMatch: true},
+		{selector: "zone==a", expectMatch: true},
+		{selector: "zone=b", expectMatch: false},
+		{selector: "zone!=b", expectMatch: true},
+		{selector: "zone=a, gpu=false", expectMatch: true},
+		{selector: "zone=a,gpu=true", expectMatch: false},
+		{selector: "gpu", expectMatch: true},
+		{selector: "!gpu", expectMatch: false},
+		{selector: "!ssd", expectMatch: true},
+		{selector: "ssd!=true", expectMatch: true},
+		{selector: "=a", expectErr: true},
+		{selector: "zone=a=b", expectErr: true},
+		{selector: "!", expectErr: true},
+		{selector: "zone=a b", expectErr: true},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%q (case %d)", c.selector, i)
+
+		selector, err := ParseLabelSelector(c.selector)
+		if c.expectErr {
+			t.Error(err)
+			continue
+		}
+
+		t.NoError(err)
+		t.Equal(c.expectMatch, selector.Matches(labels))
+	}
+}
+
+func (t *labelsSuite) Test_validateLabel() {
+	tests := []struct {
+		name      string
+		key       string
+		value     string
+		expectErr bool
+	}{
+		{name: "Valid label", key: "zone", value: "a"},
+		{name: "Empty value", key: "zone", value: ""},
+		{name: "Empty key", key: "", value: "a", expectErr: true},
+		{name: "Reserved character in key", key: "zone!", value: "a", expectErr: true},
+		{name: "Comma in value", key: "zone", value: "a,b", expectErr: true},
+		{name: "Equal sign in value", key: "zone", value: "a=b", expectErr: true},
+		{name: "Space in value", key: "zone", value: "a b", expectErr: true},
+	}
+
+	for i, c := range tests {
+		t.T().Logf("%s (case %d)", c.name, i)
+
+		err := ValidateLabel(c.key, c.value)
+		if c.expectErr {
+			t.Error(err)
+		} else {
+			t.NoError(err)
+		}
+	}
+}
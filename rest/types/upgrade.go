@@ -0,0 +1,28 @@
+package types
+
+// ClusterUpgrade represents the progress of a rolling upgrade across the cluster.
+type ClusterUpgrade struct {
+	// Converged is true if no cluster member is holding back the rest of the cluster.
+	Converged bool `json:"converged" yaml:"converged"`
+
+	// SchemaInternalVersion is the most recent internal schema version in the cluster.
+	SchemaInternalVersion uint64 `json:"schema_internal_version" yaml:"schema_internal_version"`
+
+	// SchemaExternalVersion is the most recent external schema version in the cluster.
+	SchemaExternalVersion uint64 `json:"schema_external_version" yaml:"schema_external_version"`
+
+	// Members holds the upgrade state of each cluster member.
+	Members []ClusterMemberUpgrade `json:"members" yaml:"members"`
+}
+
+// ClusterMemberUpgrade represents the upgrade state of a single cluster member.
+type ClusterMemberUpgrade struct {
+	Name                  string       `json:"name" yaml:"name"`
+	Address               AddrPort     `json:"address" yaml:"address"`
+	Version               string       `json:"version" yaml:"version"`
+	SchemaInternalVersion uint64       `json:"schema_internal_version" yaml:"schema_internal_version"`
+	SchemaExternalVersion uint64       `json:"schema_external_version" yaml:"schema_external_version"`
+	MissingExtensions     []string     `json:"missing_extensions" yaml:"missing_extensions"`
+	UpgradeStatus         MemberStatus `json:"upgrade_status" yaml:"upgrade_status"`
+	Blocking              bool         `json:"blocking" yaml:"blocking"`
+}